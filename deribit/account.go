@@ -0,0 +1,60 @@
+package deribit
+
+type Fee struct {
+	Currency       string  `json:"currency"`
+	FeeType        string  `json:"fee_type"`
+	InstrumentType string  `json:"instrument_type"`
+	MakerFee       float64 `json:"maker_fee"`
+	TakerFee       float64 `json:"taker_fee"`
+}
+
+// AccountSummary is the summary of one currency, the extended fields (fees, usd totals) are set with extended=true
+type AccountSummary struct {
+	Currency                     string                 `json:"currency"`
+	DeltaTotalMap                map[string]float64     `json:"delta_total_map"`
+	MarginBalance                float64                `json:"margin_balance"`
+	FuturesSessionRPL            float64                `json:"futures_session_rpl"`
+	OptionsSessionRPL            float64                `json:"options_session_rpl"`
+	EstimatedLiquidationRatioMap map[string]float64     `json:"estimated_liquidation_ratio_map"`
+	SessionUPL                   float64                `json:"session_upl"`
+	EstimatedLiquidationRatio    float64                `json:"estimated_liquidation_ratio"`
+	OptionsGammaMap              map[string]float64     `json:"options_gamma_map"`
+	OptionsThetaMap              map[string]float64     `json:"options_theta_map"`
+	OptionsVega                  float64                `json:"options_vega"`
+	OptionsValue                 float64                `json:"options_value"`
+	AvailableWithdrawalFunds     float64                `json:"available_withdrawal_funds"`
+	ProjectedDeltaTotal          float64                `json:"projected_delta_total"`
+	MaintenanceMargin            float64                `json:"maintenance_margin"`
+	TotalPL                      float64                `json:"total_pl"`
+	Limits                       map[string]interface{} `json:"limits"`
+	ProjectedMaintenanceMargin   float64                `json:"projected_maintenance_margin"`
+	AvailableFunds               float64                `json:"available_funds"`
+	OptionsDelta                 float64                `json:"options_delta"`
+	Balance                      float64                `json:"balance"`
+	Equity                       float64                `json:"equity"`
+	FuturesSessionUPL            float64                `json:"futures_session_upl"`
+	FeeBalance                   float64                `json:"fee_balance"`
+	OptionsSessionUPL            float64                `json:"options_session_upl"`
+	ProjectedInitialMargin       float64                `json:"projected_initial_margin"`
+	OptionsTheta                 float64                `json:"options_theta"`
+	PortfolioMarginingEnabled    bool                   `json:"portfolio_margining_enabled"`
+	CrossCollateralEnabled       bool                   `json:"cross_collateral_enabled"`
+	HasNonBlockChainEquity       bool                   `json:"has_non_block_chain_equity"`
+	MarginModel                  string                 `json:"margin_model"`
+	OptionsVegaMap               map[string]float64     `json:"options_vega_map"`
+	FuturesPL                    float64                `json:"futures_pl"`
+	OptionsPL                    float64                `json:"options_pl"`
+	InitialMargin                float64                `json:"initial_margin"`
+	SpotReserve                  float64                `json:"spot_reserve"`
+	AdditionalReserve            float64                `json:"additional_reserve"`
+	DeltaTotal                   float64                `json:"delta_total"`
+	OptionsGamma                 float64                `json:"options_gamma"`
+	SessionRPL                   float64                `json:"session_rpl"`
+	DepositAddress               string                 `json:"deposit_address"`
+	Fees                         []Fee                  `json:"fees"`
+	TotalMarginBalanceUSD        float64                `json:"total_margin_balance_usd"`
+	TotalDeltaTotalUSD           float64                `json:"total_delta_total_usd"`
+	TotalInitialMarginUSD        float64                `json:"total_initial_margin_usd"`
+	TotalMaintenanceMarginUSD    float64                `json:"total_maintenance_margin_usd"`
+	TotalEquityUSD               float64                `json:"total_equity_usd"`
+}
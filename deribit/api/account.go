@@ -1,7 +1,12 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"strconv"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
 )
 
 type AccountService struct {
@@ -11,47 +16,16 @@ type AccountService struct {
 const (
 	urlPathAccountSummaries = "/private/get_account_summaries"
 	urlPathAccountSummary   = "/private/get_account_summary"
+
+	urlPathGetTransactionLog                = "/private/get_transaction_log"
+	urlPathGetSettlementHistoryByCurrency   = "/private/get_settlement_history_by_currency"
+	urlPathGetSettlementHistoryByInstrument = "/private/get_settlement_history_by_instrument"
+
+	maxTransactionLogCount = 250
 )
 
-type AccountSummary struct {
-	Currency                     string                 `json:"currency"`
-	DeltaTotalMap                map[string]float64     `json:"delta_total_map"`
-	MarginBalance                float64                `json:"margin_balance"`
-	FuturesSessionRPL            float64                `json:"futures_session_rpl"`
-	OptionsSessionRPL            float64                `json:"options_session_rpl"`
-	EstimatedLiquidationRatioMap map[string]float64     `json:"estimated_liquidation_ratio_map"`
-	SessionUPL                   float64                `json:"session_upl"`
-	EstimatedLiquidationRatio    float64                `json:"estimated_liquidation_ratio"`
-	OptionsGammaMap              map[string]float64     `json:"options_gamma_map"`
-	OptionsVega                  float64                `json:"options_vega"`
-	OptionsValue                 float64                `json:"options_value"`
-	AvailableWithdrawalFunds     float64                `json:"available_withdrawal_funds"`
-	ProjectedDeltaTotal          float64                `json:"projected_delta_total"`
-	MaintenanceMargin            float64                `json:"maintenance_margin"`
-	TotalPL                      float64                `json:"total_pl"`
-	Limits                       map[string]interface{} `json:"limits"`
-	ProjectedMaintenanceMargin   float64                `json:"projected_maintenance_margin"`
-	AvailableFunds               float64                `json:"available_funds"`
-	OptionsDelta                 float64                `json:"options_delta"`
-	Balance                      float64                `json:"balance"`
-	Equity                       float64                `json:"equity"`
-	FuturesSessionUPL            float64                `json:"futures_session_upl"`
-	FeeBalance                   float64                `json:"fee_balance"`
-	OptionsSessionUPL            float64                `json:"options_session_upl"`
-	ProjectedInitialMargin       float64                `json:"projected_initial_margin"`
-	OptionsTheta                 float64                `json:"options_theta"`
-	PortfolioMarginingEnabled    bool                   `json:"portfolio_margining_enabled"`
-	CrossCollateralEnabled       bool                   `json:"cross_collateral_enabled"`
-	MarginModel                  string                 `json:"margin_model"`
-	OptionsVegaMap               map[string]float64     `json:"options_vega_map"`
-	FuturesPL                    float64                `json:"futures_pl"`
-	OptionsPL                    float64                `json:"options_pl"`
-	InitialMargin                float64                `json:"initial_margin"`
-	SpotReserve                  float64                `json:"spot_reserve"`
-	DeltaTotal                   float64                `json:"delta_total"`
-	OptionsGamma                 float64                `json:"options_gamma"`
-	SessionRPL                   float64                `json:"session_rpl"`
-}
+// AccountSummary is deribit.AccountSummary, shared with the ws client
+type AccountSummary = deribit.AccountSummary
 
 type AccountSummaries struct {
 	ID                               uint64           `json:"id"`
@@ -74,28 +48,22 @@ type AccountSummariesResponse struct {
 	ID      uint64           `json:"id"`
 	JSONRPC string           `json:"jsonrpc"`
 	Result  AccountSummaries `json:"result"`
-	Error   *ResponseError   `json:"error,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
 }
 
 type AccountSummaryResponse struct {
 	ID      uint64         `json:"id"`
 	JSONRPC string         `json:"jsonrpc"`
 	Result  AccountSummary `json:"result"`
-	Error   *ResponseError `json:"error,omitempty"`
+	Error   *Error         `json:"error,omitempty"`
 }
 
 // ## Get All Asset (Currency) in Account
-func (s *AccountService) GetAccountSummaries(extended bool) (*AccountSummariesResponse, error) {
+func (s *AccountService) GetAccountSummaries(ctx context.Context, extended bool) (*AccountSummariesResponse, error) {
 	var resp AccountSummariesResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?extended=%t",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathAccountSummaries,
-		extended,
-	)
+	q := newQuery().set("extended", extended)
 
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathAccountSummaries, q, &resp)
 
 	if err != nil {
 		return nil, err
@@ -108,17 +76,12 @@ func (s *AccountService) GetAccountSummaries(extended bool) (*AccountSummariesRe
 	return &resp, nil
 }
 
-func (s *AccountService) GetAccountSummary(currency string, extended bool) (*AccountSummaryResponse, error) {
+func (s *AccountService) GetAccountSummary(ctx context.Context, currency string, extended bool) (*AccountSummaryResponse, error) {
 	var resp AccountSummaryResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?currency=%s&extended=%t",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathAccountSummary,
-		currency,
-		extended,
-	)
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("currency", currency).
+		set("extended", extended)
+	err := s.client.request(ctx, urlPathAccountSummary, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -129,3 +92,236 @@ func (s *AccountService) GetAccountSummary(currency string, extended bool) (*Acc
 
 	return &resp, nil
 }
+
+// ## ----------------- Transaction log --------------
+
+// TransactionLogEntry is one line of the account ledger: trades, deliveries, settlements, transfers,
+// deposits, withdrawals, fees. Cashflow is the balance change, Change the change of the position.
+type TransactionLogEntry struct {
+	ID               int64   `json:"id"`
+	UserSeq          int64   `json:"user_seq"`
+	Timestamp        int64   `json:"timestamp"`
+	Type             string  `json:"type"`
+	Currency         string  `json:"currency"`
+	InstrumentName   string  `json:"instrument_name"`
+	Side             string  `json:"side"`
+	Amount           float64 `json:"amount"`
+	Contracts        float64 `json:"contracts"`
+	Position         float64 `json:"position"`
+	Price            float64 `json:"price"`
+	PriceCurrency    string  `json:"price_currency"`
+	IndexPrice       float64 `json:"index_price"`
+	MarkPrice        float64 `json:"mark_price"`
+	Change           float64 `json:"change"`
+	Cashflow         float64 `json:"cashflow"`
+	Balance          float64 `json:"balance"`
+	Equity           float64 `json:"equity"`
+	Commission       float64 `json:"commission"`
+	FeeBalance       float64 `json:"fee_balance"`
+	InterestPL       float64 `json:"interest_pl"`
+	TotalInterestPL  float64 `json:"total_interest_pl"`
+	SessionRPL       float64 `json:"session_rpl"`
+	SessionUPL       float64 `json:"session_upl"`
+	ProfitAsCashflow bool    `json:"profit_as_cashflow"`
+	OrderID          string  `json:"order_id"`
+	TradeID          string  `json:"trade_id"`
+	UserID           int64   `json:"user_id"`
+	Username         string  `json:"username"`
+	UserRole         string  `json:"user_role"`
+}
+
+// TransactionLogCSVHeader is the header of TransactionLogEntry.CSVRecord
+var TransactionLogCSVHeader = []string{
+	"id", "user_seq", "timestamp", "type", "currency", "instrument_name", "side", "amount", "position",
+	"price", "price_currency", "change", "cashflow", "balance", "equity", "commission", "order_id", "trade_id",
+}
+
+// ## CSVRecord is the entry for encoding/csv, floats keep every decimal
+func (e TransactionLogEntry) CSVRecord() []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		strconv.FormatInt(e.UserSeq, 10),
+		strconv.FormatInt(e.Timestamp, 10),
+		e.Type,
+		e.Currency,
+		e.InstrumentName,
+		e.Side,
+		formatDecimal(e.Amount),
+		formatDecimal(e.Position),
+		formatDecimal(e.Price),
+		e.PriceCurrency,
+		formatDecimal(e.Change),
+		formatDecimal(e.Cashflow),
+		formatDecimal(e.Balance),
+		formatDecimal(e.Equity),
+		formatDecimal(e.Commission),
+		e.OrderID,
+		e.TradeID,
+	}
+}
+
+type TransactionLogResponse struct {
+	ID      uint64 `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Result  struct {
+		// Continuation is nil on the last page
+		Continuation *int64                `json:"continuation"`
+		Logs         []TransactionLogEntry `json:"logs"`
+	} `json:"result"`
+}
+
+// ## Get the transaction log of currency between two timestamps in ms, newest first.
+// query filters by type or instrument (e.g. "trade", "settlement"), continuation 0 starts from the newest entry.
+func (s *AccountService) GetTransactionLog(
+	ctx context.Context,
+	currency string,
+	startTimestamp int64,
+	endTimestamp int64,
+	query string,
+	count int,
+	continuation int64,
+) (*TransactionLogResponse, error) {
+	q := newQuery().
+		set("currency", currency).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp).
+		setOptional("query", query).
+		setOptional("continuation", continuation)
+	if count > 0 {
+		q.set("count", count)
+	}
+
+	var resp TransactionLogResponse
+
+	err := s.client.request(ctx, urlPathGetTransactionLog, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// TransactionLog walks the transaction log of currency between two timestamps in ms, newest first,
+// following continuation until the last page. Iteration stops at the first error.
+func (s *AccountService) TransactionLog(
+	ctx context.Context,
+	currency string,
+	startTimestamp int64,
+	endTimestamp int64,
+	query string,
+) iter.Seq2[TransactionLogEntry, error] {
+	return func(yield func(TransactionLogEntry, error) bool) {
+		var continuation int64
+		for {
+			resp, err := s.GetTransactionLog(ctx, currency, startTimestamp, endTimestamp, query, maxTransactionLogCount, continuation)
+			if err != nil {
+				yield(TransactionLogEntry{}, err)
+				return
+			}
+
+			for _, entry := range resp.Result.Logs {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+
+			next := resp.Result.Continuation
+			if next == nil || len(resp.Result.Logs) == 0 {
+				return
+			}
+			if *next == continuation {
+				yield(TransactionLogEntry{}, fmt.Errorf("%w: %s transaction log at continuation %d", ErrPaginationStalled, currency, continuation))
+				return
+			}
+			continuation = *next
+		}
+	}
+}
+
+// ## ----------------- Settlement history --------------
+
+// ## Get our settlements, deliveries or bankruptcies (settlementType, empty for all) of currency, newest first.
+// The entries are LastSettlementEntry with our position and profit, continuation "" starts from the newest.
+func (s *AccountService) GetSettlementHistoryByCurrency(
+	ctx context.Context,
+	currency string,
+	settlementType string,
+	count int,
+	continuation string,
+	searchStartTimestamp int64,
+) (*LastSettlementsResponse, error) {
+	q := settlementQuery(settlementType, count, continuation, searchStartTimestamp).set("currency", currency)
+
+	return s.settlementHistory(ctx, urlPathGetSettlementHistoryByCurrency, q)
+}
+
+// ## Get our settlements, deliveries or bankruptcies of an instrument, newest first
+func (s *AccountService) GetSettlementHistoryByInstrument(
+	ctx context.Context,
+	instrumentName string,
+	settlementType string,
+	count int,
+	continuation string,
+	searchStartTimestamp int64,
+) (*LastSettlementsResponse, error) {
+	q := settlementQuery(settlementType, count, continuation, searchStartTimestamp).set("instrument_name", instrumentName)
+
+	return s.settlementHistory(ctx, urlPathGetSettlementHistoryByInstrument, q)
+}
+
+// SettlementHistoryByCurrency walks our settlement history of currency, newest first, following continuation
+func (s *AccountService) SettlementHistoryByCurrency(
+	ctx context.Context,
+	currency string,
+	settlementType string,
+	searchStartTimestamp int64,
+) iter.Seq2[LastSettlementEntry, error] {
+	return func(yield func(LastSettlementEntry, error) bool) {
+		var continuation string
+		for {
+			resp, err := s.GetSettlementHistoryByCurrency(ctx, currency, settlementType, 0, continuation, searchStartTimestamp)
+			if err != nil {
+				yield(LastSettlementEntry{}, err)
+				return
+			}
+
+			for _, entry := range resp.Result.Settlements {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+
+			next := resp.Result.Continuation
+			if next == "" || next == "none" || len(resp.Result.Settlements) == 0 {
+				return
+			}
+			if next == continuation {
+				yield(LastSettlementEntry{}, fmt.Errorf("%w: %s settlements at continuation %s", ErrPaginationStalled, currency, continuation))
+				return
+			}
+			continuation = next
+		}
+	}
+}
+
+func (s *AccountService) settlementHistory(ctx context.Context, urlPath string, q *query) (*LastSettlementsResponse, error) {
+	var resp LastSettlementsResponse
+	err := s.client.request(ctx, urlPath, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## settlementQuery has the optional filters of the settlement history methods
+func settlementQuery(settlementType string, count int, continuation string, searchStartTimestamp int64) *query {
+	q := newQuery().
+		setOptional("type", settlementType).
+		setOptional("continuation", continuation)
+	if count > 0 {
+		q.set("count", count)
+	}
+	if searchStartTimestamp > 0 {
+		q.set("search_start_timestamp", searchStartTimestamp)
+	}
+	return q
+}
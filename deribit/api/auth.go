@@ -1,56 +1,104 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"bitbucket.org/ohm89/go-deribit/deribit"
 	"github.com/valyala/fasthttp"
 )
 
-type AuthResult struct {
-	AccessToken        string   `json:"access_token"`
-	EnabledFeatures    []string `json:"enabled_features"`
-	ExpiresIn          int      `json:"expires_in"`
-	GoogleLogin        bool     `json:"google_login"`
-	MandatoryTFAStatus string   `json:"mandatory_tfa_status"`
-	RefreshToken       string   `json:"refresh_token"`
-	Scope              string   `json:"scope"`
-	SID                string   `json:"sid,omitempty"`
-	State              string   `json:"state,omitempty"`
-	TokenType          string   `json:"token_type"`
-}
+// ## Renew the access token this long before it expires, at most a tenth of its lifetime
+const tokenRenewMargin = time.Minute
 
-type AuthError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
+const urlPathAuth = "/public/auth"
+
+// ## Requests and results of authentication are the deribit types shared with the ws client
+type (
+	AuthRequest = deribit.AuthRequest
+	AuthResult  = deribit.AuthResult
+)
+
+// Deprecated: AuthError is Error
+type AuthError = Error
 
 type AuthResponse struct {
 	ID      int        `json:"id"`
 	JSONRPC string     `json:"jsonrpc"`
 	Result  AuthResult `json:"result,omitempty"`
-	Error   *AuthError `json:"error,omitempty"`
+	Error   *Error     `json:"error,omitempty"`
 }
 
-type AuthRequest struct {
-	GrantType    string `json:"grant_type"`
-	ClientID     string `json:"client_id"`
-	ClientSecret string `json:"client_secret"`
-	RefreshToken string `json:"refresh_token,omitempty"`
-	Timestamp    int64  `json:"timestamp,omitempty"`
-	Signature    string `json:"signature,omitempty"`
-	Nonce        string `json:"nonce,omitempty"`
-	Data         string `json:"data,omitempty"`
-	State        string `json:"state,omitempty"`
-	Scope        string `json:"scope,omitempty"`
-}
+// ## Authenticate opens a session with client credentials, or with a signature when WithSignatureAuth is set
+func Authenticate(ctx context.Context, c *Client) (*AuthResponse, error) {
+	if c.signatureAuth {
+		return AuthenticateWithSignature(ctx, c)
+	}
 
-func Authenticate(c *Client) (*AuthResponse, error) {
 	if c.clientID == "" || len(c.clientSecret) == 0 {
 		return nil, errors.New("API clientID and clientSecret not configured")
 	}
 
+	authRequest := &AuthRequest{
+		GrantType:    "client_credentials",
+		ClientID:     c.clientID,
+		ClientSecret: c.clientSecret,
+	}
+
+	q := newQuery().
+		set("grant_type", authRequest.GrantType).
+		set("client_id", authRequest.ClientID).
+		set("client_secret", authRequest.ClientSecret)
+
+	return sendAuth(ctx, c, authRequest, q)
+}
+
+// ## RefreshAuth exchanges the stored refresh token for a new access token
+func RefreshAuth(ctx context.Context, c *Client) (*AuthResponse, error) {
+	c.tokenMu.RLock()
+	refreshToken := c.session.refreshToken
+	c.tokenMu.RUnlock()
+
+	if refreshToken == "" {
+		return nil, errors.New("no refresh token, authenticate first")
+	}
+
+	authRequest := &AuthRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: refreshToken,
+	}
+
+	return sendAuth(ctx, c, authRequest, refreshQuery(refreshToken))
+}
+
+func refreshQuery(refreshToken string) *query {
+	return newQuery().
+		set("grant_type", "refresh_token").
+		set("refresh_token", refreshToken)
+}
+
+func sendAuth(ctx context.Context, c *Client, authRequest *AuthRequest, q *query) (*AuthResponse, error) {
+	data, err := requestToken(ctx, c, authRequest.GrantType, urlPathAuth, q)
+	if err != nil {
+		return nil, err
+	}
+
+	c.tokenMu.Lock()
+	c.session.set(data.Result)
+	c.tokenMu.Unlock()
+
+	c.logger.Debug("authenticated", "grant_type", authRequest.GrantType, "result", data.Result)
+
+	return data, nil
+}
+
+// ## requestToken sends a request that answers with tokens (auth, exchange_token, fork_token), it stores nothing.
+// The secrets are in the JSON-RPC body, in the query string only with WithGETTransport.
+func requestToken(ctx context.Context, c *Client, name string, urlPath string, q *query) (*AuthResponse, error) {
 	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
 
 	defer func() {
@@ -58,26 +106,28 @@ func Authenticate(c *Client) (*AuthResponse, error) {
 		fasthttp.ReleaseResponse(resp)
 	}()
 
-	authRequest := &AuthRequest{
-		GrantType:    "client_credentials",
-		ClientID:     c.clientID,
-		ClientSecret: c.clientSecret,
+	uri := c.uri(urlPath, q)
+	req.Header.SetMethod("GET")
+	if !c.getTransport {
+		uri = c.baseURL + defaultAPIURL
+		body, err := json.Marshal(RequestBody{
+			ID:      c.requestID.Add(1),
+			JSONRPC: jsonRPCVersion,
+			Method:  strings.TrimPrefix(urlPath, "/"),
+			Params:  q.rpcParams(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		req.Header.SetMethod("POST")
+		req.SetBody(body)
 	}
 
-	uri := fmt.Sprintf(
-		"%s%s/public/auth?grant_type=%s&client_id=%s&client_secret=%s",
-		c.baseURL,
-		defaultAPIURL,
-		authRequest.GrantType,
-		authRequest.ClientID,
-		authRequest.ClientSecret,
-	)
-
 	req.SetRequestURI(uri)
-	req.Header.SetMethod("GET")
 	req.Header.Set("Content-Type", "application/json")
 
-	if err := c.client.Do(req, resp); err != nil {
+	if err := c.doHTTP(ctx, req, resp); err != nil {
+		c.logger.Error("failed to authenticate", "grant_type", name, "uri", redactURI(uri), "error", err)
 		return nil, err
 	}
 
@@ -87,11 +137,77 @@ func Authenticate(c *Client) (*AuthResponse, error) {
 	}
 
 	if data.Error != nil {
-		return nil, fmt.Errorf("authentication failed: code: %d, message: %s", data.Error.Code, data.Error.Message)
+		return nil, fmt.Errorf("authentication failed: %w", data.Error)
 	}
 
-	c.accessToken = data.Result.AccessToken
-	c.refreshToken = data.Result.RefreshToken
-
 	return &data, nil
 }
+
+// ## session is an access token with its refresh token and when to renew it
+type session struct {
+	accessToken  string
+	refreshToken string
+	renewAt      time.Time
+}
+
+// ## set stores the tokens of an auth result, they are renewed a bit before expires_in
+func (s *session) set(result AuthResult) {
+	s.accessToken = result.AccessToken
+	if result.RefreshToken != "" {
+		s.refreshToken = result.RefreshToken
+	}
+
+	s.renewAt = time.Time{}
+	if lifetime := time.Duration(result.ExpiresIn) * time.Second; lifetime > 0 {
+		s.renewAt = time.Now().Add(lifetime - min(tokenRenewMargin, lifetime/10))
+	}
+}
+
+func (s *session) valid() bool {
+	return s.accessToken != "" && (s.renewAt.IsZero() || time.Now().Before(s.renewAt))
+}
+
+// ## token returns the access token, it is renewed first when missing or about to expire
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.tokenMu.RLock()
+	current := c.session
+	c.tokenMu.RUnlock()
+
+	if current.valid() {
+		return current.accessToken, nil
+	}
+
+	return c.renewToken(ctx, current.accessToken)
+}
+
+// ## renewToken replaces stale with a refreshed token and falls back to client credentials when the refresh fails.
+// Concurrent callers wait for the first one and reuse its token.
+func (c *Client) renewToken(ctx context.Context, stale string) (string, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	c.tokenMu.RLock()
+	current := c.session
+	c.tokenMu.RUnlock()
+
+	if current.accessToken != stale && current.valid() {
+		return current.accessToken, nil
+	}
+
+	if current.refreshToken != "" {
+		resp, err := RefreshAuth(ctx, c)
+		if err == nil {
+			return resp.Result.AccessToken, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		c.logger.Warn("failed to refresh token, authenticate with client credentials", "error", err)
+	}
+
+	resp, err := Authenticate(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	return resp.Result.AccessToken, nil
+}
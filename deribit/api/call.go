@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ## JSON-RPC over HTTP POST, the default transport of the services
+
+const jsonRPCVersion = "2.0"
+
+// ErrTransportUnsupported is returned before sending a request the transport of WithTransport cannot honour,
+// an ActAsSubaccount ctx or a WithDedup check
+var ErrTransportUnsupported = errors.New("not supported over the transport of WithTransport")
+
+// RequestBody is the JSON-RPC request sent by Call
+type RequestBody struct {
+	ID      uint64 `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Call sends method (e.g. public/get_index_price or private/get_positions) with params as a JSON-RPC POST
+// and decodes the result into result. It reaches any Deribit method, also the ones without a service method.
+// Private methods are authenticated, rate limited and retried like the services. Client is a deribit.Transport.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	return c.CallWithOptions(ctx, method, params, result)
+}
+
+// CallWithOptions is Call with per call options such as WithRetry or WithDedup
+func (c *Client) CallWithOptions(ctx context.Context, method string, params any, result any, opts ...CallOption) error {
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := c.call(ctx, method, params, &resp, opts...); err != nil {
+		return err
+	}
+
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// ## call posts method with params to /api/v2 and decodes the whole response into out
+func (c *Client) call(ctx context.Context, method string, params any, out any, opts ...CallOption) error {
+	if err := c.checkFundTransfer(method); err != nil {
+		return err
+	}
+
+	if params == nil {
+		params = map[string]any{}
+	}
+
+	if c.transport != nil {
+		return c.callTransport(ctx, method, params, out, opts...)
+	}
+
+	body := RequestBody{
+		ID:      c.requestID.Add(1),
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+		Params:  params,
+	}
+
+	uri := c.baseURL + defaultAPIURL
+	isPrivate := strings.HasPrefix(method, "private/")
+
+	return c.do(ctx, method, uri, "POST", body, out, isPrivate, opts...)
+}
+
+// ## callTransport sends method over the transport of WithTransport, which decodes the result only.
+// The result is put back in a response envelope so out is the same response type as over HTTP.
+// The transport runs on its own session without retries, a subaccount or dedup check would be silently lost.
+func (c *Client) callTransport(ctx context.Context, method string, params any, out any, opts ...CallOption) error {
+	if subaccountFrom(ctx) != 0 {
+		return fmt.Errorf("%s: %w: ActAsSubaccount", method, ErrTransportUnsupported)
+	}
+	if c.callOptions(opts).dedup != nil {
+		return fmt.Errorf("%s: %w: WithDedup", method, ErrTransportUnsupported)
+	}
+
+	var result json.RawMessage
+	if err := c.transport.Call(ctx, method, params, &result); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	if len(result) == 0 {
+		result = json.RawMessage("null")
+	}
+
+	envelope, err := json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result"`
+	}{JSONRPC: jsonRPCVersion, Result: result})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s response: %w", method, err)
+	}
+	if err := json.Unmarshal(envelope, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	return nil
+}
+
+// ## request sends the endpoint urlPath with the params of q, as JSON-RPC POST or as GET with WithGETTransport
+func (c *Client) request(ctx context.Context, urlPath string, q *query, out any, opts ...CallOption) error {
+	method := strings.TrimPrefix(urlPath, "/")
+
+	if c.getTransport && c.transport == nil {
+		if err := c.checkFundTransfer(method); err != nil {
+			return err
+		}
+		isPrivate := strings.HasPrefix(method, "private/")
+		return c.do(ctx, method, c.uri(urlPath, q), "GET", nil, out, isPrivate, opts...)
+	}
+
+	return c.call(ctx, method, q.rpcParams(), out, opts...)
+}
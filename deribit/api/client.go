@@ -1,10 +1,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"bitbucket.org/ohm89/go-deribit/deribit"
+	"bitbucket.org/ohm89/go-deribit/deribit/ratelimit"
 	"github.com/valyala/fasthttp"
 )
 
@@ -25,16 +32,14 @@ type service struct {
 	client *Client
 }
 
-type ResponseError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
+// Deprecated: ResponseError is Error
+type ResponseError = Error
 
 type Response struct {
 	Id      uint64      `json:"id"`
 	Jsonrpc string      `json:"jsonrpc"`
 	Result  interface{} `json:"result,omitempty"`
-	Error   *AuthError  `json:"error,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
 }
 
 type Client struct {
@@ -43,21 +48,48 @@ type Client struct {
 
 	clientID     string
 	clientSecret string
-	accessToken  string
-	refreshToken string
+
+	// ## Sessions are guarded by tokenMu, authMu lets one request renew them while the others wait
+	tokenMu     sync.RWMutex
+	authMu      sync.Mutex
+	session     session
+	subaccounts map[int64]*session
+
+	// ## signatureAuth signs every private request with the deri-hmac-sha256 header instead of a token
+	signatureAuth bool
+	clock         Clock
+
+	// ## fundTransfers enables withdrawals and transfers, they fail with ErrFundTransfersDisabled otherwise
+	fundTransfers bool
+
+	// ## getTransport sends the REST services as GET with a query string instead of JSON-RPC POST
+	getTransport bool
+	requestID    atomic.Uint64
+
+	// ## transport sends the services instead of HTTP when set, e.g. a ws client
+	transport deribit.Transport
+
+	// ## instruments checks order prices and amounts before sending when set
+	instruments     *InstrumentRegistry
+	normalizePolicy NormalizePolicy
 	// subaccountId uint64
 
 	common service // Reuse a single struct instead of allocating one for each service on the heap.
 
-	Accounts *AccountService
-	Markets  *MarketService
-	// Wallets  *WalletService
-	Orders *OrderService
-	// Fills    *FillsService
-	Positions *PositionService
+	Accounts    *AccountService
+	Markets     *MarketService
+	Wallets     *WalletService
+	Orders      *OrderService
+	Fills       *FillsService
+	Positions   *PositionService
+	Subaccounts *SubaccountService
+
+	logger      *slog.Logger
+	limiter     *ratelimit.Limiter
+	retryPolicy RetryPolicy
 }
 
-func New(baseUrl string, clientID string, clientSecret string) *Client {
+func New(baseUrl string, clientID string, clientSecret string, opts ...Option) *Client {
 	httpClient := &fasthttp.Client{
 		Name:         userAgent,
 		ReadTimeout:  30 * time.Second,
@@ -69,20 +101,28 @@ func New(baseUrl string, clientID string, clientSecret string) *Client {
 		client:       httpClient,
 		clientID:     clientID,
 		clientSecret: clientSecret,
+		subaccounts:  make(map[int64]*session),
+		logger:       slog.Default(),
+		retryPolicy:  DefaultRetryPolicy(),
 	}
 	c.common.client = c
 	c.Accounts = (*AccountService)(&c.common)
 	c.Markets = (*MarketService)(&c.common)
-	// c.Wallets = (*WalletService)(&c.common)
+	c.Wallets = (*WalletService)(&c.common)
 	c.Orders = (*OrderService)(&c.common)
 	c.Positions = (*PositionService)(&c.common)
-	// c.Fills = (*FillsService)(&c.common)
+	c.Fills = (*FillsService)(&c.common)
+	c.Subaccounts = (*SubaccountService)(&c.common)
+
+	for _, opt := range opts {
+		opt(c)
+	}
 
 	return c
 }
 
-// ## Basic http driver to request
-func (c *Client) do(uri string, method string, in, out interface{}, isPrivate bool) error {
+// ## Basic http driver to request, rpcMethod (e.g. private/buy) drives rate limits and retries
+func (c *Client) do(ctx context.Context, rpcMethod string, uri string, method string, in, out interface{}, isPrivate bool, opts ...CallOption) error {
 	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
 	defer func() {
 		fasthttp.ReleaseRequest(req)
@@ -105,85 +145,206 @@ func (c *Client) do(uri string, method string, in, out interface{}, isPrivate bo
 		req.SetBody(jsonData)
 	}
 
-	if isPrivate {
-		if c.accessToken == "" {
-			if _, err := Authenticate(c); err != nil {
+	if isPrivate && !c.signs(ctx) {
+		token, err := c.sessionToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	options := c.callOptions(opts)
+	started := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := c.send(ctx, req, resp, uri, rpcMethod, isPrivate)
+		if err == nil {
+			break
+		}
+
+		retry, safe := retryable(err)
+		if !retry || attempt >= options.retry.MaxAttempts {
+			return err
+		}
+
+		// ## The failed attempt may have executed, only send again when the dedup check says it did not
+		if !safe && !isIdempotent(rpcMethod) {
+			if options.dedup == nil {
 				return err
 			}
+			if dedupErr := options.dedup(ctx, started); dedupErr != nil {
+				return fmt.Errorf("%w (after: %w)", dedupErr, err)
+			}
 		}
 
-		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+		delay := options.retry.delay(attempt)
+		c.logger.Warn("request failed, retry", "method", rpcMethod, "attempt", attempt, "delay", delay, "error", err)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w (after: %w)", ctx.Err(), err)
+		}
+	}
+
+	if out != nil {
+		// Assign the data.Result to the out parameter
+		if err := json.Unmarshal(resp.Body(), out); err != nil {
+			return fmt.Errorf("unmarshal out: [%v] body: %v, error: %v", resp.StatusCode(), string(resp.Body()), err)
+		}
 	}
 
+	c.logger.Debug("request done", "method", rpcMethod, "http_method", method, "uri", redactURI(uri), "status", resp.StatusCode())
+
+	return nil
+}
+
+// ## send runs one attempt of req, it authenticates again and resends on 13009
+func (c *Client) send(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, uri string, rpcMethod string, isPrivate bool) error {
 	var numRetries int
-	var data Response
 	for {
-		if err := c.client.Do(req, resp); err != nil {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx, rpcMethod); err != nil {
+				return err
+			}
+		}
+
+		if isPrivate && c.signs(ctx) {
+			req.Header.Set("Authorization", c.signatureHeader(req))
+		}
+
+		if err := c.doHTTP(ctx, req, resp); err != nil {
 			return err
 		}
 
-		// Check the response status code
-		if resp.StatusCode() == fasthttp.StatusOK {
-			// Check if the response body is empty
-			if resp.Body() == nil || len(resp.Body()) == 0 {
-				// Return an error, as the response should not be empty
-				return fmt.Errorf("unexpected empty response body with status code %d", resp.StatusCode())
+		// Check if the response body is empty
+		if len(resp.Body()) == 0 {
+			if resp.StatusCode() != fasthttp.StatusOK {
+				return c.statusError(rpcMethod, uri, resp)
 			}
+			// Return an error, as the response should not be empty
+			return fmt.Errorf("unexpected empty response body with status code %d", resp.StatusCode())
+		}
 
-			if err := json.Unmarshal(resp.Body(), &data); err != nil {
-				return fmt.Errorf("unmarshal: [%v] body: %v, error: %v", resp.StatusCode(), string(resp.Body()), err)
+		// ## Deribit answers JSON-RPC errors with a non 200 status as well, decode them first
+		var data Response
+		if err := json.Unmarshal(resp.Body(), &data); err != nil {
+			if resp.StatusCode() != fasthttp.StatusOK {
+				return c.statusError(rpcMethod, uri, resp)
 			}
+			return fmt.Errorf("unmarshal: [%v] body: %v, error: %v", resp.StatusCode(), string(resp.Body()), err)
+		}
+
+		if data.Error != nil {
+			// Check if the error code is 13009 (unauthorized)
+			if isPrivate && !c.signs(ctx) && data.Error.Code == CodeUnauthorized && numRetries < maxRetries {
+				c.logger.Debug("token rejected, renew and retry", "uri", redactURI(uri), "retry", numRetries+1)
 
-			if data.Error != nil {
-				// Check if the error code is 13009 (unauthorized)
-				if data.Error.Code == 13009 && numRetries < maxRetries {
-					// Retry the request after authenticating
-					if _, err := Authenticate(c); err != nil {
-						return err
-					}
-					numRetries++
-					continue
+				// Retry the request with a new token
+				rejected := strings.TrimPrefix(string(req.Header.Peek("Authorization")), "Bearer ")
+				token, err := c.renewSessionToken(ctx, rejected)
+				if err != nil {
+					return err
 				}
-				return fmt.Errorf("request failed: code: %d, message: %s", data.Error.Code, data.Error.Message)
+				req.Header.Set("Authorization", "Bearer "+token)
+				numRetries++
+				continue
 			}
 
-			break
-		} else {
-			// ## [DEBUG]
-			fmt.Printf("-- do Request Error !! -- \n")
-			fmt.Printf("Error Request URL: %#v \n\n", string(req.RequestURI()))
-			fmt.Printf("Error Request Body: %#v \n\n", string(req.Body()))
-			fmt.Printf("Error Response body: %#v \n\n", string(resp.Body()))
-
-			// Handle the error response
-			return fmt.Errorf(
-				"request URI: %s \n request Body: %s \n\n Request failed with status code: %d \n Response body: %s",
-				string(req.RequestURI()),
-				string(req.Body()),
-				resp.StatusCode(),
-				string(resp.Body()),
-			)
+			if data.Error.Code == CodeTooManyRequests && c.limiter != nil {
+				c.limiter.OnRateLimited(rpcMethod)
+			}
+
+			c.logger.Debug("request failed", "method", rpcMethod, "uri", redactURI(uri), "code", data.Error.Code, "message", data.Error.Message)
+			return data.Error
 		}
+
+		if resp.StatusCode() != fasthttp.StatusOK {
+			return c.statusError(rpcMethod, uri, resp)
+		}
+
+		return nil
 	}
+}
 
-	if out != nil {
-		// Assign the data.Result to the out parameter
-		if err := json.Unmarshal(resp.Body(), out); err != nil {
-			return fmt.Errorf("unmarshal out: [%v] body: %v, error: %v", resp.StatusCode(), string(resp.Body()), err)
+// ## doHTTP runs req until ctx is done, fasthttp has no context so the request runs on copies that
+// are released once it finishes even when ctx was cancelled first
+func (c *Client) doHTTP(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if ctx.Done() == nil {
+		return c.client.Do(req, resp)
+	}
+
+	ctxReq, ctxResp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	req.CopyTo(ctxReq)
+
+	errCh := make(chan error, 1)
+	go func() {
+		if deadline, ok := ctx.Deadline(); ok {
+			errCh <- c.client.DoDeadline(ctxReq, ctxResp, deadline)
+		} else {
+			errCh <- c.client.Do(ctxReq, ctxResp)
 		}
+	}()
+
+	select {
+	case err := <-errCh:
+		ctxResp.CopyTo(resp)
+		fasthttp.ReleaseRequest(ctxReq)
+		fasthttp.ReleaseResponse(ctxResp)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	case <-ctx.Done():
+		go func() {
+			<-errCh
+			fasthttp.ReleaseRequest(ctxReq)
+			fasthttp.ReleaseResponse(ctxResp)
+		}()
+		return ctx.Err()
 	}
+}
 
-	// ## [DEBUG]
-	// fmt.Printf("-- do Request Done !! -- \n")
-	// fmt.Printf("Received a message: %#v \n\n", out)
+// ## methodOf returns the JSON-RPC method of a request uri, e.g. private/buy
+func methodOf(uri string) string {
+	path, _, _ := strings.Cut(uri, "?")
+	if _, after, found := strings.Cut(path, defaultAPIURL+"/"); found {
+		return after
+	}
+	return path
+}
 
-	return nil
+// ## statusError logs and returns a non 200 response without JSON-RPC error
+func (c *Client) statusError(method string, uri string, resp *fasthttp.Response) error {
+	c.logger.Warn(
+		"unexpected status code",
+		"method", method,
+		"uri", redactURI(uri),
+		"status", resp.StatusCode(),
+		"response", string(resp.Body()),
+	)
+
+	return &StatusError{
+		StatusCode: resp.StatusCode(),
+		URI:        redactURI(uri),
+		Body:       string(resp.Body()),
+	}
 }
 
-func (c *Client) DoPublic(uri string, method string, in, out interface{}) error {
-	return c.do(uri, method, in, out, false)
+func (c *Client) DoPublic(ctx context.Context, uri string, method string, in, out interface{}, opts ...CallOption) error {
+	return c.do(ctx, methodOf(uri), uri, method, in, out, false, opts...)
 }
 
-func (c *Client) DoPrivate(uri string, method string, in, out interface{}) error {
-	return c.do(uri, method, in, out, true)
+func (c *Client) DoPrivate(ctx context.Context, uri string, method string, in, out interface{}, opts ...CallOption) error {
+	if err := c.checkFundTransfer(methodOf(uri)); err != nil {
+		return err
+	}
+	return c.do(ctx, methodOf(uri), uri, method, in, out, true, opts...)
 }
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"bitbucket.org/ohm89/go-deribit/deribit"
+)
+
+// Decimal is deribit.Decimal, a price, amount or trigger written with every digit and never in exponent form
+type Decimal = deribit.Decimal
+
+// ParseDecimal reads a decimal as written by String, an exponent is accepted too
+func ParseDecimal(s string) (Decimal, error) {
+	return deribit.ParseDecimal(s)
+}
+
+// NewDecimal returns a pointer to value, e.g. for the Price of OrderRequest and EditRequest
+func NewDecimal(value float64) *Decimal {
+	return deribit.NewDecimal(value)
+}
+
+// ## formatDecimal writes a float query parameter like Decimal, %f would round to 6 digits
+func formatDecimal(value float64) string {
+	return Decimal(value).String()
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
+)
+
+// ## Deribit JSON-RPC error codes
+const (
+	CodeOrderNotFound      = deribit.CodeOrderNotFound
+	CodeNotEnoughFunds     = deribit.CodeNotEnoughFunds
+	CodeTooManyRequests    = deribit.CodeTooManyRequests
+	CodeRetry              = deribit.CodeRetry
+	CodePriceWrongTick     = deribit.CodePriceWrongTick
+	CodeInvalidCredentials = deribit.CodeInvalidCredentials
+	CodeUnauthorized       = deribit.CodeUnauthorized
+)
+
+// ## Sentinels for errors.Is, shared with the ws package
+var (
+	ErrOrderNotFound      = deribit.ErrOrderNotFound
+	ErrNotEnoughFunds     = deribit.ErrNotEnoughFunds
+	ErrTooManyRequests    = deribit.ErrTooManyRequests
+	ErrRetry              = deribit.ErrRetry
+	ErrPriceWrongTick     = deribit.ErrPriceWrongTick
+	ErrInvalidCredentials = deribit.ErrInvalidCredentials
+	ErrUnauthorized       = deribit.ErrUnauthorized
+)
+
+// Error is the JSON-RPC error object returned by Deribit, the same type as ws.Error
+// so errors.Is and errors.As work the same for both transports.
+type (
+	Error     = deribit.Error
+	ErrorData = deribit.ErrorData
+)
+
+// StatusError is a non 200 HTTP response that carries no JSON-RPC error
+type StatusError struct {
+	StatusCode int
+	URI        string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request URI: %s \n Request failed with status code: %d \n Response body: %s", e.URI, e.StatusCode, e.Body)
+}
+
+// ## ErrorCode returns the Deribit code of err, 0 when err is not a Deribit error
+func ErrorCode(err error) int {
+	return deribit.ErrorCode(err)
+}
+
+func IsRateLimited(err error) bool {
+	return deribit.IsRateLimited(err)
+}
+
+func IsInsufficientFunds(err error) bool {
+	return deribit.IsInsufficientFunds(err)
+}
+
+func IsOrderNotFound(err error) bool {
+	return deribit.IsOrderNotFound(err)
+}
+
+func IsUnauthorized(err error) bool {
+	return deribit.IsUnauthorized(err)
+}
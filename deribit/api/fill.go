@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+type FillsService struct {
+	client *Client
+}
+
+const (
+	urlPathGetUserTradesByCurrency          = "/private/get_user_trades_by_currency"
+	urlPathGetUserTradesByCurrencyAndTime   = "/private/get_user_trades_by_currency_and_time"
+	urlPathGetUserTradesByInstrument        = "/private/get_user_trades_by_instrument"
+	urlPathGetUserTradesByInstrumentAndTime = "/private/get_user_trades_by_instrument_and_time"
+	urlPathGetUserTradesByOrder             = "/private/get_user_trades_by_order"
+)
+
+// maxUserTradesCount is the largest page Deribit returns for user trades
+const maxUserTradesCount = 1000
+
+// UserTrade is one of our own fills, it has the same fields as the trades of an order response
+type UserTrade struct {
+	OrderResultTradeResponse
+}
+
+type UserTradesResult struct {
+	HasMore bool        `json:"has_more"`
+	Trades  []UserTrade `json:"trades"`
+}
+
+type UserTradesResponse struct {
+	ID      uint64           `json:"id"`
+	JSONRPC string           `json:"jsonrpc"`
+	Result  UserTradesResult `json:"result"`
+}
+
+type UserTradesByOrderResponse struct {
+	ID      uint64      `json:"id"`
+	JSONRPC string      `json:"jsonrpc"`
+	Result  []UserTrade `json:"result"`
+}
+
+// ## ------------------------------------------------------------------------
+
+// UserTradesByCurrencyRequest selects our trades of a currency, every field but Currency is optional.
+// GetUserTradesByCurrencyAndTime requires StartTimestamp and EndTimestamp and does not use the ids and SubaccountID.
+type UserTradesByCurrencyRequest struct {
+	Currency       string
+	Kind           string
+	StartID        string
+	EndID          string
+	Count          int
+	StartTimestamp int64
+	EndTimestamp   int64
+	Sorting        string
+	Historical     bool
+	SubaccountID   int
+}
+
+// UserTradesByInstrumentRequest selects our trades of an instrument, every field but InstrumentName is optional.
+// GetUserTradesByInstrumentAndTime requires StartTimestamp and EndTimestamp and does not use the seqs.
+type UserTradesByInstrumentRequest struct {
+	InstrumentName string
+	StartSeq       int
+	EndSeq         int
+	Count          int
+	StartTimestamp int64
+	EndTimestamp   int64
+	Sorting        string
+	Historical     bool
+}
+
+// GetUserTradesByCurrency retrieves our latest trades of a currency
+func (s *FillsService) GetUserTradesByCurrency(ctx context.Context, request *UserTradesByCurrencyRequest) (*UserTradesResponse, error) {
+	var resp UserTradesResponse
+	q := newQuery().
+		set("currency", request.Currency).
+		setOptional("kind", request.Kind).
+		setOptional("start_id", request.StartID).
+		setOptional("end_id", request.EndID).
+		setOptional("count", request.Count).
+		setOptional("start_timestamp", request.StartTimestamp).
+		setOptional("end_timestamp", request.EndTimestamp).
+		setOptional("sorting", request.Sorting).
+		setOptional("historical", request.Historical).
+		setOptional("subaccount_id", request.SubaccountID)
+
+	err := s.client.request(ctx, urlPathGetUserTradesByCurrency, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetUserTradesByCurrencyAndTime retrieves our trades of a currency between two timestamps in ms
+func (s *FillsService) GetUserTradesByCurrencyAndTime(ctx context.Context, request *UserTradesByCurrencyRequest) (*UserTradesResponse, error) {
+	var resp UserTradesResponse
+	q := newQuery().
+		set("currency", request.Currency).
+		set("start_timestamp", request.StartTimestamp).
+		set("end_timestamp", request.EndTimestamp).
+		setOptional("kind", request.Kind).
+		setOptional("count", request.Count).
+		setOptional("sorting", request.Sorting).
+		setOptional("historical", request.Historical)
+
+	err := s.client.request(ctx, urlPathGetUserTradesByCurrencyAndTime, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetUserTradesByInstrument retrieves our trades of an instrument
+func (s *FillsService) GetUserTradesByInstrument(ctx context.Context, request *UserTradesByInstrumentRequest) (*UserTradesResponse, error) {
+	var resp UserTradesResponse
+	q := newQuery().
+		set("instrument_name", request.InstrumentName).
+		setOptional("start_seq", request.StartSeq).
+		setOptional("end_seq", request.EndSeq).
+		setOptional("count", request.Count).
+		setOptional("start_timestamp", request.StartTimestamp).
+		setOptional("end_timestamp", request.EndTimestamp).
+		setOptional("sorting", request.Sorting).
+		setOptional("historical", request.Historical)
+
+	err := s.client.request(ctx, urlPathGetUserTradesByInstrument, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetUserTradesByInstrumentAndTime retrieves our trades of an instrument between two timestamps in ms
+func (s *FillsService) GetUserTradesByInstrumentAndTime(ctx context.Context, request *UserTradesByInstrumentRequest) (*UserTradesResponse, error) {
+	var resp UserTradesResponse
+	q := newQuery().
+		set("instrument_name", request.InstrumentName).
+		set("start_timestamp", request.StartTimestamp).
+		set("end_timestamp", request.EndTimestamp).
+		setOptional("count", request.Count).
+		setOptional("sorting", request.Sorting).
+		setOptional("historical", request.Historical)
+
+	err := s.client.request(ctx, urlPathGetUserTradesByInstrumentAndTime, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetUserTradesByOrder retrieves the trades of one order
+func (s *FillsService) GetUserTradesByOrder(ctx context.Context, orderID string, sorting string, historical bool) (*UserTradesByOrderResponse, error) {
+	var resp UserTradesByOrderResponse
+	q := newQuery().set("order_id", orderID)
+
+	if sorting != "" {
+		q.set("sorting", sorting)
+	}
+	if historical {
+		q.set("historical", true)
+	}
+
+	err := s.client.request(ctx, urlPathGetUserTradesByOrder, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## ----------------- Pagination --------------
+
+// ErrPaginationStalled is returned when a full page brings no new trade, e.g. more trades share one timestamp than fit a page
+var ErrPaginationStalled = errors.New("pagination stalled")
+
+// UserTradesByInstrument walks all our trades of an instrument from startSeq (0 for the first) in ascending
+// trade_seq, fetching the next page with start_seq while has_more is set. Iteration stops at the first error.
+func (s *FillsService) UserTradesByInstrument(ctx context.Context, instrumentName string, startSeq int, historical bool) iter.Seq2[UserTrade, error] {
+	return func(yield func(UserTrade, error) bool) {
+		for {
+			resp, err := s.GetUserTradesByInstrument(ctx, &UserTradesByInstrumentRequest{
+				InstrumentName: instrumentName,
+				StartSeq:       startSeq,
+				Count:          maxUserTradesCount,
+				Sorting:        "asc",
+				Historical:     historical,
+			})
+			if err != nil {
+				yield(UserTrade{}, err)
+				return
+			}
+
+			trades := resp.Result.Trades
+			for _, trade := range trades {
+				if !yield(trade, nil) {
+					return
+				}
+			}
+
+			if !resp.Result.HasMore || len(trades) == 0 {
+				return
+			}
+			startSeq = trades[len(trades)-1].TradeSeq + 1
+		}
+	}
+}
+
+// UserTradesByCurrencyAndTime walks all our trades of a currency between two timestamps in ms in ascending time,
+// the next page starts at the last timestamp and trades already yielded at that timestamp are skipped.
+func (s *FillsService) UserTradesByCurrencyAndTime(
+	ctx context.Context,
+	currency string,
+	kind string,
+	startTimestamp int64,
+	endTimestamp int64,
+	historical bool,
+) iter.Seq2[UserTrade, error] {
+	return func(yield func(UserTrade, error) bool) {
+		seen := map[string]struct{}{}
+
+		for {
+			resp, err := s.GetUserTradesByCurrencyAndTime(ctx, &UserTradesByCurrencyRequest{
+				Currency:       currency,
+				Kind:           kind,
+				StartTimestamp: startTimestamp,
+				EndTimestamp:   endTimestamp,
+				Count:          maxUserTradesCount,
+				Sorting:        "asc",
+				Historical:     historical,
+			})
+			if err != nil {
+				yield(UserTrade{}, err)
+				return
+			}
+
+			trades := resp.Result.Trades
+			lastTimestamp := startTimestamp
+			yielded := 0
+			for _, trade := range trades {
+				if trade.Timestamp != lastTimestamp {
+					lastTimestamp = trade.Timestamp
+					clear(seen)
+				}
+				if _, ok := seen[trade.TradeID]; ok {
+					continue
+				}
+				seen[trade.TradeID] = struct{}{}
+
+				yielded++
+				if !yield(trade, nil) {
+					return
+				}
+			}
+
+			if !resp.Result.HasMore || len(trades) == 0 {
+				return
+			}
+			if yielded == 0 {
+				yield(UserTrade{}, fmt.Errorf("%w: %s trades at %d", ErrPaginationStalled, currency, startTimestamp))
+				return
+			}
+			startTimestamp = lastTimestamp
+		}
+	}
+}
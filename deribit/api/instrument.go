@@ -0,0 +1,336 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ## ----------------- Tick size and lot size --------------
+
+// TickSizeAt returns the tick size of the instrument at price, tick_size_steps apply above their price
+func (i *InstrumentResult) TickSizeAt(price float64) float64 {
+	tick := i.TickSize
+	above := math.Inf(-1)
+	for _, step := range i.TickSizeSteps {
+		if price > step.AbovePrice && step.AbovePrice > above {
+			tick = step.TickSize
+			above = step.AbovePrice
+		}
+	}
+	return tick
+}
+
+// RoundPrice rounds price to the nearest tick of the instrument
+func (i *InstrumentResult) RoundPrice(price float64) float64 {
+	tick := i.TickSizeAt(price)
+	if tick <= 0 {
+		return price
+	}
+	return roundTo(math.Round(price/tick)*tick, tick)
+}
+
+// AmountStep is the increment of order amounts, min_trade_amount and contract_size otherwise
+func (i *InstrumentResult) AmountStep() float64 {
+	if i.MinTradeAmount > 0 {
+		return i.MinTradeAmount
+	}
+	return i.ContractSize
+}
+
+// RoundAmount rounds amount down to a multiple of the amount step, an order never grows by rounding
+func (i *InstrumentResult) RoundAmount(amount float64) float64 {
+	step := i.AmountStep()
+	if step <= 0 {
+		return amount
+	}
+	// ## The epsilon keeps 0.3/0.1 = 2.9999999999999996 at 3 steps
+	return roundTo(math.Floor(amount/step+1e-9)*step, step)
+}
+
+// ## roundTo drops the float noise of a multiple of step, e.g. 0.30000000000000004 with a step of 0.1
+func roundTo(value float64, step float64) float64 {
+	decimals := 0
+	if _, fraction, found := strings.Cut(strconv.FormatFloat(step, 'f', -1, 64), "."); found {
+		decimals = len(fraction)
+	}
+	scale := math.Pow10(decimals)
+	return math.Round(value*scale) / scale
+}
+
+// ## onStep tells whether value is already a multiple of step
+func onStep(value float64, rounded float64, step float64) bool {
+	return math.Abs(value-rounded) <= step*1e-9
+}
+
+// ## ----------------- Registry --------------
+
+// NormalizePolicy is what order placement does with a price or amount that is not on the instrument grid
+type NormalizePolicy int
+
+const (
+	// NormalizeReject returns an ErrInvalidOrder error before sending
+	NormalizeReject NormalizePolicy = iota
+	// NormalizeRound rounds prices to the nearest tick and amounts down to the amount step, in the request
+	NormalizeRound
+)
+
+type instrumentSource struct {
+	currency string
+	kind     string
+}
+
+// InstrumentRegistry caches instruments by name. Load adds the instruments of a currency and kind,
+// Refresh reloads them so new instruments appear and expired ones go, unknown names are fetched on first use.
+type InstrumentRegistry struct {
+	markets *MarketService
+
+	mu          sync.RWMutex
+	instruments map[string]InstrumentResult
+	sources     map[instrumentSource][]string
+}
+
+func NewInstrumentRegistry(markets *MarketService) *InstrumentRegistry {
+	return &InstrumentRegistry{
+		markets:     markets,
+		instruments: make(map[string]InstrumentResult),
+		sources:     make(map[instrumentSource][]string),
+	}
+}
+
+// ## Load the active instruments of currency (or "any") and kind (empty for all) and keep them refreshed
+func (r *InstrumentRegistry) Load(ctx context.Context, currency string, kind string) error {
+	resp, err := r.markets.GetInstruments(ctx, currency, kind, false)
+	if err != nil {
+		return fmt.Errorf("failed to load %s %s instruments: %w", currency, kind, err)
+	}
+
+	source := instrumentSource{currency: currency, kind: kind}
+	names := make([]string, 0, len(resp.Result))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range r.sources[source] {
+		delete(r.instruments, name)
+	}
+	for _, instrument := range resp.Result {
+		r.instruments[instrument.InstrumentName] = instrument
+		names = append(names, instrument.InstrumentName)
+	}
+	r.sources[source] = names
+
+	return nil
+}
+
+// ## Refresh reloads every currency and kind given to Load
+func (r *InstrumentRegistry) Refresh(ctx context.Context) error {
+	r.mu.RLock()
+	sources := make([]instrumentSource, 0, len(r.sources))
+	for source := range r.sources {
+		sources = append(sources, source)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].currency+sources[i].kind < sources[j].currency+sources[j].kind
+	})
+
+	var errs []error
+	for _, source := range sources {
+		if err := r.Load(ctx, source.currency, source.kind); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ## RefreshRegular refreshes the registry every interval until ctx is done, failures keep the old instruments
+func (r *InstrumentRegistry) RefreshRegular(ctx context.Context, interval time.Duration) {
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := r.Refresh(ctx); err != nil {
+					r.markets.client.logger.Warn("failed to refresh instruments", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// ## Instrument returns a cached instrument
+func (r *InstrumentRegistry) Instrument(instrumentName string) (InstrumentResult, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	instrument, found := r.instruments[instrumentName]
+	return instrument, found
+}
+
+// ## lookup returns the instrument, fetching it when it is not cached
+func (r *InstrumentRegistry) lookup(ctx context.Context, instrumentName string) (InstrumentResult, error) {
+	if instrument, found := r.Instrument(instrumentName); found {
+		return instrument, nil
+	}
+
+	resp, err := r.markets.GetInstrument(ctx, instrumentName)
+	if err != nil {
+		return InstrumentResult{}, fmt.Errorf("failed to load instrument %s: %w", instrumentName, err)
+	}
+
+	r.mu.Lock()
+	r.instruments[instrumentName] = resp.Result
+	r.mu.Unlock()
+
+	return resp.Result, nil
+}
+
+// RoundPrice rounds price to the nearest tick of the instrument
+func (r *InstrumentRegistry) RoundPrice(ctx context.Context, instrumentName string, price float64) (float64, error) {
+	instrument, err := r.lookup(ctx, instrumentName)
+	if err != nil {
+		return 0, err
+	}
+	return instrument.RoundPrice(price), nil
+}
+
+// RoundAmount rounds amount down to the amount step of the instrument
+func (r *InstrumentRegistry) RoundAmount(ctx context.Context, instrumentName string, amount float64) (float64, error) {
+	instrument, err := r.lookup(ctx, instrumentName)
+	if err != nil {
+		return 0, err
+	}
+	return instrument.RoundAmount(amount), nil
+}
+
+// ## normalizePrice checks or rounds a price field, zero means not set
+func normalizePrice(instrument *InstrumentResult, name string, value *Decimal, policy NormalizePolicy) error {
+	price := float64(*value)
+	if price == 0 {
+		return nil
+	}
+
+	tick := instrument.TickSizeAt(price)
+	rounded := instrument.RoundPrice(price)
+	if onStep(price, rounded, tick) {
+		return nil
+	}
+	if policy == NormalizeRound {
+		*value = Decimal(rounded)
+		return nil
+	}
+	return fmt.Errorf("%w: %s %s is not a multiple of tick size %s of %s", ErrInvalidOrder, name, *value, formatDecimal(tick), instrument.InstrumentName)
+}
+
+// ## normalizeAmount checks or rounds an amount, zero means not set
+func normalizeAmount(instrument *InstrumentResult, value *Decimal, policy NormalizePolicy) error {
+	amount := float64(*value)
+	if amount == 0 {
+		return nil
+	}
+
+	step := instrument.AmountStep()
+	rounded := instrument.RoundAmount(amount)
+	if !onStep(amount, rounded, step) {
+		if policy != NormalizeRound {
+			return fmt.Errorf("%w: amount %s is not a multiple of %s of %s", ErrInvalidOrder, *value, formatDecimal(step), instrument.InstrumentName)
+		}
+		amount = rounded
+		*value = Decimal(rounded)
+	}
+
+	if amount < instrument.MinTradeAmount {
+		return fmt.Errorf("%w: amount %s is below the minimum %s of %s", ErrInvalidOrder, *value, formatDecimal(instrument.MinTradeAmount), instrument.InstrumentName)
+	}
+	return nil
+}
+
+// ## NormalizeOrder checks the price, trigger price and amount of request against its instrument,
+// with NormalizeRound it fixes them in request instead of failing
+func (r *InstrumentRegistry) NormalizeOrder(ctx context.Context, request *OrderRequest, policy NormalizePolicy) error {
+	instrument, err := r.lookup(ctx, request.InstrumentName)
+	if err != nil {
+		return err
+	}
+
+	// ## An advanced price is implied volatility or USD, it is not on the tick grid
+	if request.Advanced == "" && request.Price != nil {
+		if err := normalizePrice(&instrument, "price", request.Price, policy); err != nil {
+			return err
+		}
+	}
+	if err := normalizePrice(&instrument, "trigger_price", &request.TriggerPrice, policy); err != nil {
+		return err
+	}
+	if err := normalizeAmount(&instrument, &request.Amount, policy); err != nil {
+		return err
+	}
+
+	// ## The linked orders are on the instrument of the primary order
+	for i := range request.OTOCOConfig {
+		config := &request.OTOCOConfig[i]
+		if config.Price != nil {
+			if err := normalizePrice(&instrument, fmt.Sprintf("otoco_config[%d].price", i), config.Price, policy); err != nil {
+				return err
+			}
+		}
+		if err := normalizePrice(&instrument, fmt.Sprintf("otoco_config[%d].trigger_price", i), &config.TriggerPrice, policy); err != nil {
+			return err
+		}
+		if err := normalizeAmount(&instrument, &config.Amount, policy); err != nil {
+			return fmt.Errorf("otoco_config[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ## NormalizeEdit is NormalizeOrder for an edit, the instrument comes from InstrumentName or,
+// for an edit by order id, from GetOrderState with the client of the registry
+func (r *InstrumentRegistry) NormalizeEdit(ctx context.Context, request *EditRequest, policy NormalizePolicy) error {
+	instrumentName := request.InstrumentName
+	if instrumentName == "" {
+		var err error
+		if instrumentName, err = orderInstrument(ctx, r.markets.client.Orders, request.OrderID); err != nil {
+			return err
+		}
+	}
+	return r.normalizeEdit(ctx, instrumentName, request, policy)
+}
+
+// ## orderInstrument looks up the instrument of an order, order ids are prefixed with the currency only
+func orderInstrument(ctx context.Context, orders *OrderService, orderID string) (string, error) {
+	resp, err := orders.GetOrderState(ctx, orderID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the instrument of order %s: %w", orderID, err)
+	}
+	return resp.Result.InstrumentName, nil
+}
+
+func (r *InstrumentRegistry) normalizeEdit(ctx context.Context, instrumentName string, request *EditRequest, policy NormalizePolicy) error {
+	instrument, err := r.lookup(ctx, instrumentName)
+	if err != nil {
+		return err
+	}
+
+	// ## An advanced price is implied volatility or USD, it is not on the tick grid
+	if request.Advanced == "" && request.Price != nil {
+		if err := normalizePrice(&instrument, "price", request.Price, policy); err != nil {
+			return err
+		}
+	}
+	if err := normalizePrice(&instrument, "trigger_price", &request.TriggerPrice, policy); err != nil {
+		return err
+	}
+	return normalizeAmount(&instrument, &request.Amount, policy)
+}
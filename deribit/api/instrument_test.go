@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decodeInstrument(t *testing.T, data string) *InstrumentResult {
+	t.Helper()
+
+	var instrument InstrumentResult
+	if err := json.Unmarshal([]byte(data), &instrument); err != nil {
+		t.Fatal(err)
+	}
+	return &instrument
+}
+
+// ## A BTC option: 0.0001 up to 0.005, then 0.0005
+const optionJSON = `{
+	"instrument_name": "BTC-27DEC24-100000-C",
+	"tick_size": 0.0001,
+	"tick_size_steps": [{"above_price": 0.005, "tick_size": 0.0005}],
+	"min_trade_amount": 0.1,
+	"contract_size": 1
+}`
+
+func TestTickSizeAt(t *testing.T) {
+	option := decodeInstrument(t, optionJSON)
+	perpetual := decodeInstrument(t, `{"instrument_name": "BTC-PERPETUAL", "tick_size": 0.5, "contract_size": 10}`)
+	steps := decodeInstrument(t, `{
+		"tick_size": 0.01,
+		"tick_size_steps": [{"above_price": 100, "tick_size": 1}, {"above_price": 10, "tick_size": 0.1}]
+	}`)
+
+	tests := []struct {
+		name       string
+		instrument *InstrumentResult
+		price      float64
+		want       float64
+	}{
+		{"below the step", option, 0.0042, 0.0001},
+		{"at the step", option, 0.005, 0.0001},
+		{"above the step", option, 0.0123, 0.0005},
+		{"no steps", perpetual, 65000, 0.5},
+		{"negative without steps", perpetual, -12.5, 0.5},
+		{"negative below every step", option, -0.02, 0.0001},
+		{"zero", option, 0, 0.0001},
+		{"unsorted steps, lowest", steps, 5, 0.01},
+		{"unsorted steps, middle", steps, 50, 0.1},
+		{"unsorted steps, highest", steps, 500, 1},
+	}
+
+	for _, tt := range tests {
+		if got := tt.instrument.TickSizeAt(tt.price); got != tt.want {
+			t.Errorf("%s: TickSizeAt(%v) = %v, want %v", tt.name, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestRoundPrice(t *testing.T) {
+	option := decodeInstrument(t, optionJSON)
+	perpetual := decodeInstrument(t, `{"instrument_name": "BTC-PERPETUAL", "tick_size": 0.5, "contract_size": 10}`)
+	noTick := decodeInstrument(t, `{"instrument_name": "X"}`)
+
+	tests := []struct {
+		name       string
+		instrument *InstrumentResult
+		price      float64
+		want       float64
+	}{
+		{"on the tick", perpetual, 65000.5, 65000.5},
+		{"down to the tick", perpetual, 65000.2, 65000},
+		{"up to the tick", perpetual, 65000.3, 65000.5},
+		{"small tick without float noise", option, 0.00037, 0.0004},
+		{"coarse tick above the step", option, 0.0123, 0.0125},
+		{"negative down", perpetual, -12.3, -12.5},
+		{"negative up", perpetual, -12.2, -12},
+		{"zero", perpetual, 0, 0},
+		{"no tick size", noTick, 1.23456, 1.23456},
+	}
+
+	for _, tt := range tests {
+		if got := tt.instrument.RoundPrice(tt.price); got != tt.want {
+			t.Errorf("%s: RoundPrice(%v) = %v, want %v", tt.name, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	option := decodeInstrument(t, optionJSON)
+	perpetual := decodeInstrument(t, `{"instrument_name": "BTC-PERPETUAL", "contract_size": 10}`)
+	noStep := decodeInstrument(t, `{"instrument_name": "X"}`)
+
+	tests := []struct {
+		name       string
+		instrument *InstrumentResult
+		amount     float64
+		want       float64
+	}{
+		{"on the step", option, 0.3, 0.3},
+		{"down to the step", option, 0.37, 0.3},
+		{"never up", option, 0.19999, 0.1},
+		{"contract size without min_trade_amount", perpetual, 125, 120},
+		{"below the minimum", option, 0.05, 0},
+		{"below the contract size", perpetual, 9, 0},
+		{"zero", option, 0, 0},
+		{"no step", noStep, 1.234, 1.234},
+	}
+
+	for _, tt := range tests {
+		if got := tt.instrument.RoundAmount(tt.amount); got != tt.want {
+			t.Errorf("%s: RoundAmount(%v) = %v, want %v", tt.name, tt.amount, got, tt.want)
+		}
+	}
+}
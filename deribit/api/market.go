@@ -1,6 +1,9 @@
 package api
 
-import "fmt"
+import (
+	"context"
+	"iter"
+)
 
 type MarketService struct {
 	client *Client
@@ -27,6 +30,10 @@ const (
 	urlPathGetTradingViewChartData = "/public/get_tradingview_chart_data"
 	urlPathGetVolatilityIndexData  = "/public/get_volatility_index_data"
 	urlPathGetTicker               = "/public/ticker"
+	urlPathGetTime                 = "/public/get_time"
+	urlPathGetDeliveryPrices       = "/public/get_delivery_prices"
+
+	maxDeliveryPricesCount = 1000
 )
 
 // ## ------------------------------------------------------------------------
@@ -63,16 +70,12 @@ type FundingChartDataResponse struct {
 }
 
 // GetFundingChartData retrieves the funding chart data for the specified instrument and time period.
-func (s *MarketService) GetFundingChartData(request *FundingChartDataRequest) (*FundingChartDataResponse, error) {
+func (s *MarketService) GetFundingChartData(ctx context.Context, request *FundingChartDataRequest) (*FundingChartDataResponse, error) {
 	var resp FundingChartDataResponse
-	uri := fmt.Sprintf("%s%s%s?instrument_name=%s&length=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetFundingChartData,
-		request.InstrumentName,
-		request.Length,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", request.InstrumentName).
+		set("length", request.Length)
+	err := s.client.request(ctx, urlPathGetFundingChartData, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -104,21 +107,17 @@ type FundingRateHistoryResponse struct {
 
 // GetFundingRateHistory retrieves the funding rate history for the specified instrument.
 func (s *MarketService) GetFundingRateHistory(
+	ctx context.Context,
 	instrumentName string,
 	startTimestamp int64,
 	endTimestamp int64,
 ) (*FundingRateHistoryResponse, error) {
 	var resp FundingRateHistoryResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s&start_timestamp=%d&end_timestamp=%d",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetFundingRateHistory,
-		instrumentName,
-		startTimestamp,
-		endTimestamp,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp)
+	err := s.client.request(ctx, urlPathGetFundingRateHistory, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -136,20 +135,17 @@ type FundingRateValueResponse struct {
 
 // GetFundingRateValue retrieves the current funding rate for the specified instrument.
 func (s *MarketService) GetFundingRateValue(
+	ctx context.Context,
 	instrumentName string,
 	startTimestamp int64,
 	endTimestamp int64,
 ) (*FundingRateValueResponse, error) {
 	var resp FundingRateValueResponse
-	uri := fmt.Sprintf("%s%s%s?instrument_name=%s&start_timestamp=%d&end_timestamp=%d",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetFundingRateValue,
-		instrumentName,
-		startTimestamp,
-		endTimestamp,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp)
+	err := s.client.request(ctx, urlPathGetFundingRateValue, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -166,15 +162,10 @@ type HistoricalVolatilityResponse struct {
 }
 
 // GetHistoricalVolatility retrieves the historical volatility for the specified currency.
-func (s *MarketService) GetHistoricalVolatility(currency string) (*HistoricalVolatilityResponse, error) {
+func (s *MarketService) GetHistoricalVolatility(ctx context.Context, currency string) (*HistoricalVolatilityResponse, error) {
 	var resp HistoricalVolatilityResponse
-	uri := fmt.Sprintf("%s%s%s?currency=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetHistoricalVolatility,
-		currency,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().set("currency", currency)
+	err := s.client.request(ctx, urlPathGetHistoricalVolatility, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -195,15 +186,10 @@ type IndexPriceResponse struct {
 }
 
 // GetIndexPrice retrieves the index price for the specified index name.
-func (s *MarketService) GetIndexPrice(indexName string) (*IndexPriceResponse, error) {
+func (s *MarketService) GetIndexPrice(ctx context.Context, indexName string) (*IndexPriceResponse, error) {
 	var resp IndexPriceResponse
-	uri := fmt.Sprintf("%s%s%s?index_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetIndexPrice,
-		indexName,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().set("index_name", indexName)
+	err := s.client.request(ctx, urlPathGetIndexPrice, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -219,14 +205,9 @@ type IndexPriceNamesResponse struct {
 }
 
 // GetIndexPriceNames retrieves the list of available index price names.
-func (s *MarketService) GetIndexPriceNames() (*IndexPriceNamesResponse, error) {
+func (s *MarketService) GetIndexPriceNames(ctx context.Context) (*IndexPriceNamesResponse, error) {
 	var resp IndexPriceNamesResponse
-	uri := fmt.Sprintf("%s%s%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetIndexPriceNames,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetIndexPriceNames, nil, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -273,15 +254,10 @@ type InstrumentResponse struct {
 }
 
 // GetInstrument retrieves the details of the specified instrument.
-func (s *MarketService) GetInstrument(instrumentName string) (*InstrumentResponse, error) {
+func (s *MarketService) GetInstrument(ctx context.Context, instrumentName string) (*InstrumentResponse, error) {
 	var resp InstrumentResponse
-	uri := fmt.Sprintf("%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetInstrument,
-		instrumentName,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().set("instrument_name", instrumentName)
+	err := s.client.request(ctx, urlPathGetInstrument, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -297,18 +273,14 @@ type InstrumentsResponse struct {
 	Result  []InstrumentResult `json:"result"`
 }
 
-// GetInstruments retrieves a list of available instruments.
-func (s *MarketService) GetInstruments(currency string, kind string, expired bool) (*InstrumentsResponse, error) {
+// GetInstruments retrieves a list of available instruments, an empty kind returns every kind.
+func (s *MarketService) GetInstruments(ctx context.Context, currency string, kind string, expired bool) (*InstrumentsResponse, error) {
 	var resp InstrumentsResponse
-	uri := fmt.Sprintf("%s%s%s?currency=%s&kind=%s&expired=%t",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetInstruments,
-		currency,
-		kind,
-		expired,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("currency", currency).
+		setOptional("kind", kind).
+		set("expired", expired)
+	err := s.client.request(ctx, urlPathGetInstruments, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -346,6 +318,7 @@ type LastSettlementsResponse struct {
 
 // GetLastSettlementsByInstrument retrieves the last settlements for the specified instrument.
 func (s *MarketService) GetLastSettlementsByInstrument(
+	ctx context.Context,
 	instrumentName string,
 	settlementType string,
 	count int,
@@ -353,17 +326,13 @@ func (s *MarketService) GetLastSettlementsByInstrument(
 	searchStartTimestamp int64,
 ) (*LastSettlementsResponse, error) {
 	var resp LastSettlementsResponse
-	uri := fmt.Sprintf("%s%s%s?instrument_name=%s&type=%s&count=%d&continuation=%s&search_start_timestamp=%d",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetLastSettlementsByInstrument,
-		instrumentName,
-		settlementType,
-		count,
-		continuation,
-		searchStartTimestamp,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		setOptional("type", settlementType).
+		setOptional("count", count).
+		setOptional("continuation", continuation).
+		setOptional("search_start_timestamp", searchStartTimestamp)
+	err := s.client.request(ctx, urlPathGetLastSettlementsByInstrument, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -405,22 +374,19 @@ type LastTradesByCurrencyAndTimeResponse struct {
 
 // GetLastTradesByCurrencyAndTime retrieves the last trades for the specified currency and time range.
 func (s *MarketService) GetLastTradesByCurrencyAndTime(
+	ctx context.Context,
 	currency string,
 	startTimestamp int64,
 	endTimestamp int64,
 	count int,
 ) (*LastTradesByCurrencyAndTimeResponse, error) {
 	var resp LastTradesByCurrencyAndTimeResponse
-	uri := fmt.Sprintf("%s%s%s?currency=%s&start_timestamp=%d&end_timestamp=%d&count=%d",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetLastTradeByCurrencyAndTime,
-		currency,
-		startTimestamp,
-		endTimestamp,
-		count,
-	)
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("currency", currency).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp).
+		set("count", count)
+	err := s.client.request(ctx, urlPathGetLastTradeByCurrencyAndTime, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -441,6 +407,7 @@ type LastTradesByInstrumentResponse struct {
 
 // GetLastTradesByInstrument retrieves the last trades for the specified instrument.
 func (s *MarketService) GetLastTradesByInstrument(
+	ctx context.Context,
 	instrumentName string,
 	startSeq int,
 	endSeq int,
@@ -450,34 +417,28 @@ func (s *MarketService) GetLastTradesByInstrument(
 	sorting string,
 ) (*LastTradesByInstrumentResponse, error) {
 	var resp LastTradesByInstrumentResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetLastTradeByInstrument,
-		instrumentName,
-	)
+	q := newQuery().set("instrument_name", instrumentName)
 
 	if startSeq != 0 {
-		uri += fmt.Sprintf("&start_seq=%d", startSeq)
+		q.set("start_seq", startSeq)
 	}
 	if endSeq != 0 {
-		uri += fmt.Sprintf("&end_seq=%d", endSeq)
+		q.set("end_seq", endSeq)
 	}
 	if startTimestamp != 0 {
-		uri += fmt.Sprintf("&start_timestamp=%d", startTimestamp)
+		q.set("start_timestamp", startTimestamp)
 	}
 	if endTimestamp != 0 {
-		uri += fmt.Sprintf("&end_timestamp=%d", endTimestamp)
+		q.set("end_timestamp", endTimestamp)
 	}
 	if count != 0 {
-		uri += fmt.Sprintf("&count=%d", count)
+		q.set("count", count)
 	}
 	if sorting != "" {
-		uri += fmt.Sprintf("&sorting=%s", sorting)
+		q.set("sorting", sorting)
 	}
 
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetLastTradeByInstrument, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -499,6 +460,7 @@ type GetLastTradesByInstrumentAndTimeResponse struct {
 
 // GetLastTradesByInstrumentAndTime retrieves the last trades for the specified instrument and time range.
 func (s *MarketService) GetLastTradesByInstrumentAndTime(
+	ctx context.Context,
 	instrumentName string,
 	startTimestamp int64,
 	endTimestamp int64,
@@ -506,24 +468,19 @@ func (s *MarketService) GetLastTradesByInstrumentAndTime(
 	sorting string,
 ) (*GetLastTradesByInstrumentAndTimeResponse, error) {
 	var resp GetLastTradesByInstrumentAndTimeResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s&start_timestamp=%d&end_timestamp=%d",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetLastTradeByInstrumentAndTime,
-		instrumentName,
-		startTimestamp,
-		endTimestamp,
-	)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp)
 
 	if count != 0 {
-		uri += fmt.Sprintf("&count=%d", count)
+		q.set("count", count)
 	}
 	if sorting != "" {
-		uri += fmt.Sprintf("&sorting=%s", sorting)
+		q.set("sorting", sorting)
 	}
 
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetLastTradeByInstrumentAndTime, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -542,23 +499,19 @@ type MarkPriceHistoryResponse struct {
 
 // GetMarkPriceHistory retrieves the mark price history for the specified instrument.
 func (s *MarketService) GetMarkPriceHistory(
+	ctx context.Context,
 	instrumentName string,
 	startTimestamp int64,
 	endTimestamp int64,
 
 ) (*MarkPriceHistoryResponse, error) {
 	var resp MarkPriceHistoryResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s&start_timestamp=%d&end_timestamp=%d",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetMarkPriceHistory,
-		instrumentName,
-		startTimestamp,
-		endTimestamp,
-	)
-
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp)
+
+	err := s.client.request(ctx, urlPathGetMarkPriceHistory, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -578,6 +531,7 @@ type OrderBookResult struct {
 	BestBidPrice   float64     `json:"best_bid_price"`
 	BidIV          float64     `json:"bid_iv"`
 	Bids           [][]float64 `json:"bids"`
+	ChangeID       int64       `json:"change_id"`
 	CurrentFunding float64     `json:"current_funding"`
 	DeliveryPrice  float64     `json:"delivery_price"`
 	Funding8h      float64     `json:"funding_8h"`
@@ -620,23 +574,18 @@ type OrderBookResponse struct {
 
 // GetOrderBook retrieves the order book for the specified instrument.
 func (s *MarketService) GetOrderBook(
+	ctx context.Context,
 	instrumentName string,
 	depth int,
 ) (*OrderBookResponse, error) {
 	var resp OrderBookResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOrderBook,
-		instrumentName,
-	)
+	q := newQuery().set("instrument_name", instrumentName)
 
 	if depth > 0 {
-		uri += fmt.Sprintf("&depth=%d", depth)
+		q.set("depth", depth)
 	}
 
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetOrderBook, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -655,23 +604,18 @@ type GetOrderBookByInstrumentResponse struct {
 
 // GetOrderBookByInstrumentId retrieves the order book for the specified instrument ID.
 func (s *MarketService) GetOrderBookByInstrumentId(
+	ctx context.Context,
 	instrumentID int,
 	depth int,
 ) (*GetOrderBookByInstrumentResponse, error) {
 	var resp GetOrderBookByInstrumentResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_id=%d",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOrderBookByInstrumentId,
-		instrumentID,
-	)
+	q := newQuery().set("instrument_id", instrumentID)
 
 	if depth > 0 {
-		uri += fmt.Sprintf("&depth=%d", depth)
+		q.set("depth", depth)
 	}
 
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetOrderBookByInstrumentId, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -707,21 +651,17 @@ type GetTradeVolumesResponse struct {
 
 // GetTradeVolumes retrieves the trade volumes for the specified currency.
 func (s *MarketService) GetTradeVolumes(
+	ctx context.Context,
 	extended bool,
 ) (*GetTradeVolumesResponse, error) {
 	var resp GetTradeVolumesResponse
-	uri := fmt.Sprintf(
-		"%s%s%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetTradeVolumes,
-	)
+	q := newQuery()
 
 	if extended {
-		uri += "?extended=true"
+		q.set("extended", true)
 	}
 
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetTradeVolumes, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -755,24 +695,20 @@ type TradingViewChartDataResponse struct {
 
 // GetTradingViewChartData retrieves the trading view chart data for the specified instrument.
 func (s *MarketService) GetTradingViewChartData(
+	ctx context.Context,
 	instrumentName string,
 	startTimestamp int64,
 	endTimestamp int64,
 	resolution string,
 ) (*TradingViewChartDataResponse, error) {
 	var resp TradingViewChartDataResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s&start_timestamp=%d&end_timestamp=%d&resolution=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetTradingViewChartData,
-		instrumentName,
-		startTimestamp,
-		endTimestamp,
-		resolution,
-	)
-
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp).
+		set("resolution", resolution)
+
+	err := s.client.request(ctx, urlPathGetTradingViewChartData, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -803,24 +739,20 @@ type VolatilityIndexDataResponse struct {
 
 // GetVolatilityIndexData retrieves the volatility index data for the specified instrument.
 func (s *MarketService) GetVolatilityIndexData(
+	ctx context.Context,
 	currency string,
 	startTimestamp int64,
 	endTimestamp int64,
 	resolution string,
 ) (*VolatilityIndexDataResponse, error) {
 	var resp VolatilityIndexDataResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?currency=%s&start_timestamp=%d&end_timestamp=%d&resolution=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetVolatilityIndexData,
-		currency,
-		startTimestamp,
-		endTimestamp,
-		resolution,
-	)
-
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("currency", currency).
+		set("start_timestamp", startTimestamp).
+		set("end_timestamp", endTimestamp).
+		set("resolution", resolution)
+
+	err := s.client.request(ctx, urlPathGetVolatilityIndexData, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -867,21 +799,91 @@ type TickerResponse struct {
 
 // GetTicker retrieves the ticker data for the specified instrument.
 func (s *MarketService) GetTicker(
+	ctx context.Context,
 	instrumentName string,
 ) (*TickerResponse, error) {
 	var resp TickerResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetTicker,
-		instrumentName,
-	)
-
-	err := s.client.DoPublic(uri, "GET", nil, &resp)
+	q := newQuery().set("instrument_name", instrumentName)
+
+	err := s.client.request(ctx, urlPathGetTicker, q, &resp)
 	if err != nil {
 		return nil, err
 	}
 
 	return &resp, nil
 }
+
+// ## ------------------------------------------------------------------------
+
+// TimeResponse is the current server time in milliseconds since the Unix epoch
+type TimeResponse struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Result  int64  `json:"result"`
+}
+
+// GetTime retrieves the current time of the Deribit server.
+func (s *MarketService) GetTime(ctx context.Context) (*TimeResponse, error) {
+	var resp TimeResponse
+	err := s.client.request(ctx, urlPathGetTime, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## ------------------------------------------------------------------------
+
+// DeliveryPrice is the delivery price of an index on a date formatted YYYY-MM-DD
+type DeliveryPrice struct {
+	Date          string  `json:"date"`
+	DeliveryPrice float64 `json:"delivery_price"`
+}
+
+type DeliveryPricesResponse struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Result  struct {
+		Data         []DeliveryPrice `json:"data"`
+		RecordsTotal int             `json:"records_total"`
+	} `json:"result"`
+}
+
+// GetDeliveryPrices retrieves the delivery prices of an index (e.g. btc_usd), newest first.
+func (s *MarketService) GetDeliveryPrices(ctx context.Context, indexName string, offset int, count int) (*DeliveryPricesResponse, error) {
+	var resp DeliveryPricesResponse
+	q := newQuery().
+		set("index_name", indexName).
+		set("offset", offset).
+		set("count", count)
+	err := s.client.request(ctx, urlPathGetDeliveryPrices, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeliveryPrices walks every delivery price of an index, newest first
+func (s *MarketService) DeliveryPrices(ctx context.Context, indexName string) iter.Seq2[DeliveryPrice, error] {
+	return func(yield func(DeliveryPrice, error) bool) {
+		offset := 0
+		for {
+			resp, err := s.GetDeliveryPrices(ctx, indexName, offset, maxDeliveryPricesCount)
+			if err != nil {
+				yield(DeliveryPrice{}, err)
+				return
+			}
+
+			for _, price := range resp.Result.Data {
+				if !yield(price, nil) {
+					return
+				}
+			}
+
+			offset += len(resp.Result.Data)
+			if len(resp.Result.Data) == 0 || offset >= resp.Result.RecordsTotal {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
+	"bitbucket.org/ohm89/go-deribit/deribit/ratelimit"
+)
+
+// redacted replaces secrets in logs and errors
+const redacted = "[REDACTED]"
+
+// secretParams are the query parameters never written to logs or errors
+var secretParams = []string{"client_secret", "refresh_token", "access_token", "signature"}
+
+// Option configures a Client in New
+type Option func(c *Client)
+
+// WithLogger sends the client logs to logger, the default is slog.Default().
+// Request URIs are logged with client_secret and tokens redacted.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithRateLimiter makes every request wait for credits of limiter, share it with the ws client of the same account
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithRetryPolicy sets the retry policy of every call, WithRetry overrides it per call
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithSignatureAuth signs private requests with the deri-hmac-sha256 Authorization header and
+// authenticates with grant_type=client_signature, the client secret is never sent
+func WithSignatureAuth() Option {
+	return func(c *Client) {
+		c.signatureAuth = true
+	}
+}
+
+// WithClockOffset is the offset added to the local time of signatures, SyncClock measures it
+func WithClockOffset(offset time.Duration) Option {
+	return func(c *Client) {
+		c.clock.SetOffset(offset)
+	}
+}
+
+// WithFundTransfers enables the methods that move funds: private/withdraw, private/submit_transfer_to_subaccount
+// and private/submit_transfer_to_user, from WalletService, Call and DoPrivate alike.
+// Without it they return ErrFundTransfersDisabled before sending anything.
+func WithFundTransfers() Option {
+	return func(c *Client) {
+		c.fundTransfers = true
+	}
+}
+
+// WithInstrumentRegistry makes order placement and edits check price, trigger price and amount, also of the
+// otoco_config orders, against the tick size and amount step of the instrument. NormalizeReject fails with
+// ErrInvalidOrder, NormalizeRound fixes the values in the request before sending.
+// An edit by order id first gets the instrument of the order with GetOrderState.
+func WithInstrumentRegistry(registry *InstrumentRegistry, policy NormalizePolicy) Option {
+	return func(c *Client) {
+		c.instruments = registry
+		c.normalizePolicy = policy
+	}
+}
+
+// WithGETTransport sends the services as GET requests with a query string, the transport before JSON-RPC POST.
+// Parameters and secrets are then part of the URL.
+func WithGETTransport() Option {
+	return func(c *Client) {
+		c.getTransport = true
+	}
+}
+
+// WithTransport sends the services through transport instead of HTTP, e.g. an authenticated ws.DeribitClient
+// so OrderService, MarketService and PositionService run over its WebSocket connection.
+// The transport authenticates, rate limits and reconnects itself, requests are sent once without the retry policy.
+// An ActAsSubaccount ctx or a WithDedup option fail with ErrTransportUnsupported instead of running on the
+// session of the transport.
+func WithTransport(transport deribit.Transport) Option {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// ## redactURI masks the secret query parameters of uri
+func redactURI(uri string) string {
+	base, rawQuery, found := strings.Cut(uri, "?")
+	if !found {
+		return uri
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		for _, secret := range secretParams {
+			if key == secret {
+				params[i] = key + "=" + redacted
+				break
+			}
+		}
+	}
+
+	return base + "?" + strings.Join(params, "&")
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"strings"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
 )
 
 type OrderService struct {
@@ -12,9 +15,15 @@ type OrderService struct {
 const (
 	urlPathBuy                         = "/private/buy"
 	urlPathSell                        = "/private/sell"
+	urlPathEdit                        = "/private/edit"
+	urlPathEditByLabel                 = "/private/edit_by_label"
 	urlPathCancelOneOrder              = "/private/cancel"
 	urlPathCancelAllOrder              = "/private/cancel_all"
 	urlPathCancelAllByInstrument       = "/private/cancel_all_by_instrument"
+	urlPathCancelByLabel               = "/private/cancel_by_label"
+	urlPathCancelAllByCurrency         = "/private/cancel_all_by_currency"
+	urlPathCancelAllByKindOrType       = "/private/cancel_all_by_kind_or_type"
+	urlPathCancelQuotes                = "/private/cancel_quotes"
 	urlPathGetOrderState               = "/private/get_order_state"
 	urlPathGetOrderStateByLabel        = "/private/get_order_state_by_label"
 	urlPathGetOpenOrders               = "/private/get_open_orders"
@@ -25,21 +34,20 @@ const (
 	urlPathGetMargins                  = "/private/get_margins"
 )
 
-type OTOCOConfig struct {
-	Amount         float64 `json:"amount,omitempty"`
-	Direction      string  `json:"direction"`
-	Type           string  `json:"type,omitempty"`
-	Label          string  `json:"label,omitempty"`
-	Price          float64 `json:"price,omitempty"`
-	ReduceOnly     bool    `json:"reduce_only,omitempty"`
-	TimeInForce    string  `json:"time_in_force,omitempty"`
-	PostOnly       bool    `json:"post_only,omitempty"`
-	RejectPostOnly bool    `json:"reject_post_only,omitempty"`
-	TriggerPrice   float64 `json:"trigger_price,omitempty"`
-	TriggerOffset  float64 `json:"trigger_offset,omitempty"`
-	Trigger        string  `json:"trigger,omitempty"`
-}
+// ## Requests and results of orders are the deribit types shared with the ws client
+type (
+	OTOCOConfig              = deribit.OTOCOConfig
+	OrderRequest             = deribit.OrderRequest
+	EditRequest              = deribit.EditRequest
+	OrderResultOrderResponse = deribit.OrderResultOrderResponse
+	OrderResultTradeResponse = deribit.OrderResultTradeResponse
+	OrderResultResponse      = deribit.OrderResultResponse
+	CancelReport             = deribit.CancelReport
+	CancelResult             = deribit.CancelResult
+	CancelQuotesRequest      = deribit.CancelQuotesRequest
+)
 
+// Deprecated: PostBuy and PostSell send a RequestBody
 type OrderRequestBody struct {
 	ID      int          `json:"id,omitempty"`
 	Method  string       `json:"method"`
@@ -47,127 +55,6 @@ type OrderRequestBody struct {
 	Params  OrderRequest `json:"params"`
 }
 
-type OrderRequest struct {
-	InstrumentName       string        `json:"instrument_name"`
-	Amount               float64       `json:"amount,omitempty"`
-	Contracts            int64         `json:"contracts,omitempty"`
-	Type                 string        `json:"type,omitempty"`
-	Label                string        `json:"label,omitempty"`
-	Price                float64       `json:"price,omitempty"`
-	TimeInForce          string        `json:"time_in_force,omitempty"`
-	MaxShow              int64         `json:"max_show,omitempty"`
-	PostOnly             bool          `json:"post_only,omitempty"`
-	RejectPostOnly       bool          `json:"reject_post_only,omitempty"`
-	ReduceOnly           bool          `json:"reduce_only,omitempty"`
-	TriggerPrice         float64       `json:"trigger_price,omitempty"`
-	TriggerOffset        float64       `json:"trigger_offset,omitempty"`
-	Trigger              string        `json:"trigger,omitempty"`
-	Advanced             string        `json:"advanced,omitempty"`
-	MMP                  bool          `json:"mmp,omitempty"`
-	ValidUntil           int64         `json:"valid_until,omitempty"`
-	LinkedOrderType      string        `json:"linked_order_type,omitempty"`
-	TriggerFillCondition string        `json:"trigger_fill_condition,omitempty"`
-	OTOCOConfig          []OTOCOConfig `json:"otoco_config,omitempty"`
-}
-
-type OrderResultOrderResponse struct {
-	Quote                 bool     `json:"quote"`
-	Triggered             bool     `json:"triggered"`
-	Mobile                bool     `json:"mobile,omitempty"`
-	AppName               string   `json:"app_name,omitempty"`
-	Implv                 float64  `json:"implv,omitempty"`
-	USD                   float64  `json:"usd,omitempty"`
-	OtoOrderIds           []string `json:"oto_order_ids"`
-	API                   bool     `json:"api"`
-	AveragePrice          float64  `json:"average_price"`
-	Advanced              string   `json:"advanced,omitempty"`
-	OrderID               string   `json:"order_id"`
-	PostOnly              bool     `json:"post_only"`
-	FilledAmount          float64  `json:"filled_amount"`
-	Trigger               string   `json:"trigger,omitempty"`
-	TriggerOrderID        string   `json:"trigger_order_id,omitempty"`
-	Direction             string   `json:"direction"`
-	Contracts             float64  `json:"contracts,omitempty"`
-	IsSecondaryOto        bool     `json:"is_secondary_oto,omitempty"`
-	Replaced              bool     `json:"replaced"`
-	MMPGroup              string   `json:"mmp_group,omitempty"`
-	MMP                   bool     `json:"mmp"`
-	LastUpdateTimestamp   int64    `json:"last_update_timestamp"`
-	CreationTimestamp     int64    `json:"creation_timestamp"`
-	CancelReason          string   `json:"cancel_reason,omitempty"`
-	MMPCancelled          bool     `json:"mmp_cancelled,omitempty"`
-	QuoteID               string   `json:"quote_id,omitempty"`
-	OrderState            string   `json:"order_state"`
-	IsRebalance           bool     `json:"is_rebalance,omitempty"`
-	RejectPostOnly        bool     `json:"reject_post_only,omitempty"`
-	Label                 string   `json:"label,omitempty"`
-	IsLiquidation         bool     `json:"is_liquidation,omitempty"`
-	Price                 float64  `json:"price"`
-	Web                   bool     `json:"web,omitempty"`
-	TimeInForce           string   `json:"time_in_force"`
-	TriggerReferencePrice float64  `json:"trigger_reference_price,omitempty"`
-	OrderType             string   `json:"order_type"`
-	IsPrimaryOtoco        bool     `json:"is_primary_otoco,omitempty"`
-	OriginalOrderType     string   `json:"original_order_type,omitempty"`
-	BlockTrade            bool     `json:"block_trade,omitempty"`
-	TriggerPrice          float64  `json:"trigger_price,omitempty"`
-	OcoRef                string   `json:"oco_ref,omitempty"`
-	TriggerOffset         float64  `json:"trigger_offset,omitempty"`
-	QuoteSetID            string   `json:"quote_set_id,omitempty"`
-	AutoReplaced          bool     `json:"auto_replaced,omitempty"`
-	ReduceOnly            bool     `json:"reduce_only,omitempty"`
-	MaxShow               float64  `json:"max_show,omitempty"`
-	Amount                float64  `json:"amount"`
-	RiskReducing          bool     `json:"risk_reducing,omitempty"`
-	InstrumentName        string   `json:"instrument_name"`
-	TriggerFillCondition  string   `json:"trigger_fill_condition,omitempty"`
-	PrimaryOrderID        string   `json:"primary_order_id,omitempty"`
-}
-
-type OrderResultTradeResponse struct {
-	TradeID         string                     `json:"trade_id"`
-	TickDirection   int                        `json:"tick_direction"`
-	FeeCurrency     string                     `json:"fee_currency"`
-	API             bool                       `json:"api"`
-	Advanced        string                     `json:"advanced,omitempty"`
-	OrderID         string                     `json:"order_id"`
-	Liquidity       string                     `json:"liquidity"`
-	PostOnly        bool                       `json:"post_only"`
-	Direction       string                     `json:"direction"`
-	Contracts       float64                    `json:"contracts,omitempty"`
-	MMP             bool                       `json:"mmp"`
-	Fee             float64                    `json:"fee"`
-	QuoteID         string                     `json:"quote_id,omitempty"`
-	IndexPrice      float64                    `json:"index_price"`
-	Label           string                     `json:"label,omitempty"`
-	BlockTradeID    string                     `json:"block_trade_id,omitempty"`
-	Price           float64                    `json:"price"`
-	ComboID         string                     `json:"combo_id,omitempty"`
-	MatchingID      string                     `json:"matching_id"`
-	OrderType       string                     `json:"order_type"`
-	ProfitLoss      float64                    `json:"profit_loss"`
-	Timestamp       int64                      `json:"timestamp"`
-	IV              float64                    `json:"iv,omitempty"`
-	State           string                     `json:"state"`
-	UnderlyingPrice float64                    `json:"underlying_price,omitempty"`
-	QuoteSetID      string                     `json:"quote_set_id,omitempty"`
-	MarkPrice       float64                    `json:"mark_price"`
-	BlockRFQID      int                        `json:"block_rfq_id,omitempty"`
-	ComboTradeID    int                        `json:"combo_trade_id,omitempty"`
-	ReduceOnly      bool                       `json:"reduce_only"`
-	Amount          float64                    `json:"amount"`
-	Liquidation     string                     `json:"liquidation,omitempty"`
-	TradeSeq        int                        `json:"trade_seq"`
-	RiskReducing    bool                       `json:"risk_reducing"`
-	InstrumentName  string                     `json:"instrument_name"`
-	Legs            []OrderResultTradeResponse `json:"legs,omitempty"`
-}
-
-type OrderResultResponse struct {
-	Order  OrderResultOrderResponse   `json:"order"`
-	Trades []OrderResultTradeResponse `json:"trades"`
-}
-
 type OrderResponse struct {
 	Id      uint64              `json:"id"`
 	Jsonrpc string              `json:"jsonrpc"`
@@ -181,9 +68,15 @@ type CancelAllResponse struct {
 }
 
 type CancelAllByInstrumentResponse struct {
-	ID      uint64 `json:"id"`
-	JSONRPC string `json:"jsonrpc"`
-	Result  int    `json:"result"`
+	ID      uint64       `json:"id"`
+	JSONRPC string       `json:"jsonrpc"`
+	Result  CancelResult `json:"result"`
+}
+
+type CancelResponse struct {
+	ID      uint64       `json:"id"`
+	JSONRPC string       `json:"jsonrpc"`
+	Result  CancelResult `json:"result"`
 }
 
 type OrderState struct {
@@ -312,400 +205,184 @@ type GetTriggerOrderHistoryResponse struct {
 
 // ## --------------------------------------------------------------------------
 
-// ## Create Buy Order
-func (s *OrderService) Buy(
-	instrumentName string,
-	amount float64,
-	contracts int64,
-	orderType string,
-	label string,
-	price float64,
-	timeInForce string,
-	maxShow int64,
-	postOnly bool,
-	rejectPostOnly bool,
-	reduceOnly bool,
-	triggerPrice float64,
-	triggerOffset float64,
-	trigger string,
-	advanced string,
-	mmp bool,
-	validUntil int64,
-	linkedOrderType string,
-	triggerFillCondition string,
-	otocoConfig []OTOCOConfig,
-) (*OrderResponse, error) {
-	var resp OrderResponse
-	uri := fmt.Sprintf("%s%s%s", s.client.baseURL, defaultAPIURL, urlPathBuy)
-	queryParams := make([]string, 0, 20)
-
-	if instrumentName != "" {
-		queryParams = append(queryParams, fmt.Sprintf("instrument_name=%s", instrumentName))
-	}
-	if amount != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("amount=%f", amount))
-	}
-	if contracts != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("contracts=%d", contracts))
-	}
-	if orderType != "" {
-		queryParams = append(queryParams, fmt.Sprintf("type=%s", orderType))
-	}
-	if label != "" {
-		queryParams = append(queryParams, fmt.Sprintf("label=%s", label))
-	}
-	if price != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("price=%f", price))
-	}
-	if timeInForce != "" {
-		queryParams = append(queryParams, fmt.Sprintf("time_in_force=%s", timeInForce))
-	}
-	if maxShow != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("max_show=%d", maxShow))
-	}
-	if postOnly {
-		queryParams = append(queryParams, "post_only=true")
-	}
-	if rejectPostOnly {
-		queryParams = append(queryParams, "reject_post_only=true")
-	}
-	if reduceOnly {
-		queryParams = append(queryParams, "reduce_only=true")
-	}
-	if triggerPrice != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("trigger_price=%f", triggerPrice))
-	}
-	if triggerOffset != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("trigger_offset=%f", triggerOffset))
-	}
-	if trigger != "" {
-		queryParams = append(queryParams, fmt.Sprintf("trigger=%s", trigger))
-	}
-	if advanced != "" {
-		queryParams = append(queryParams, fmt.Sprintf("advanced=%s", advanced))
+// ## orderQuery holds the params of an order for the buy and sell endpoints, otoco_config is one JSON array
+func orderQuery(r *OrderRequest) (*query, error) {
+	q := newQuery().
+		setOptional("instrument_name", r.InstrumentName).
+		setOptional("amount", r.Amount).
+		setOptional("contracts", r.Contracts).
+		setOptional("type", r.Type).
+		setOptional("label", r.Label).
+		setOptional("price", r.Price).
+		setOptional("time_in_force", r.TimeInForce).
+		setOptional("max_show", r.MaxShow).
+		setOptional("post_only", r.PostOnly).
+		setOptional("reject_post_only", r.RejectPostOnly).
+		setOptional("reduce_only", r.ReduceOnly).
+		setOptional("trigger_price", r.TriggerPrice).
+		setOptional("trigger_offset", r.TriggerOffset).
+		setOptional("trigger", r.Trigger).
+		setOptional("advanced", r.Advanced).
+		setOptional("mmp", r.MMP).
+		setOptional("valid_until", r.ValidUntil).
+		setOptional("linked_order_type", r.LinkedOrderType).
+		setOptional("trigger_fill_condition", r.TriggerFillCondition)
+
+	if len(r.OTOCOConfig) > 0 {
+		if err := q.setJSON("otoco_config", r.OTOCOConfig); err != nil {
+			return nil, err
+		}
 	}
-	if mmp {
-		queryParams = append(queryParams, "mmp=true")
+
+	return q, nil
+}
+
+// ## editQuery holds the params of an edit for the edit and edit_by_label endpoints
+func editQuery(r *EditRequest) *query {
+	q := newQuery().
+		setOptional("order_id", r.OrderID).
+		setOptional("label", r.Label).
+		setOptional("instrument_name", r.InstrumentName).
+		setOptional("amount", r.Amount).
+		setOptional("contracts", r.Contracts).
+		setOptional("price", r.Price).
+		setOptional("reject_post_only", r.RejectPostOnly).
+		setOptional("advanced", r.Advanced).
+		setOptional("trigger_price", r.TriggerPrice).
+		setOptional("trigger_offset", r.TriggerOffset).
+		setOptional("mmp", r.MMP).
+		setOptional("valid_until", r.ValidUntil)
+
+	if r.PostOnly != nil {
+		q.set("post_only", *r.PostOnly)
 	}
-	if validUntil != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("valid_until=%d", validUntil))
+	if r.ReduceOnly != nil {
+		q.set("reduce_only", *r.ReduceOnly)
 	}
-	if linkedOrderType != "" {
-		queryParams = append(queryParams, fmt.Sprintf("linked_order_type=%s", linkedOrderType))
+
+	return q
+}
+
+// ## With WithLabelDedup an order with a label can be retried after an ambiguous failure,
+// the orders already carrying the label are listed first so only a new one counts as placed
+func (s *OrderService) orderCallOptions(ctx context.Context, request *OrderRequest, opts []CallOption) []CallOption {
+	options := s.client.callOptions(opts)
+	// ## WithTransport sends once, there is no retry to check
+	if !options.labelDedup || options.dedup != nil || request.Label == "" || s.client.transport != nil {
+		return opts
 	}
-	if triggerFillCondition != "" {
-		queryParams = append(queryParams, fmt.Sprintf("trigger_fill_condition=%s", triggerFillCondition))
+
+	known, err := s.labelOrders(ctx, request.InstrumentName, request.Label)
+	if err != nil {
+		// ## Without the list an ambiguous failure is returned instead of retried
+		s.client.logger.Warn("failed to list the orders of label, no dedup check", "label", request.Label, "error", err)
+		return opts
 	}
+	return append(opts, WithDedup(s.labelDedup(request.InstrumentName, request.Label, known)))
+}
 
-	if len(otocoConfig) > 0 {
-		for _, config := range otocoConfig {
-			if config.Amount != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=amount:%f", config.Amount))
-			}
-			if config.Direction != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=direction:%s", config.Direction))
-			}
-			if config.Type != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=type:%s", config.Type))
-			}
-			if config.Label != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=label:%s", config.Label))
-			}
-			if config.Price != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=price:%f", config.Price))
-			}
-			if config.ReduceOnly {
-				queryParams = append(queryParams, "otoco_config[]=reduce_only:true")
-			}
-			if config.TimeInForce != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=time_in_force:%s", config.TimeInForce))
-			}
-			if config.PostOnly {
-				queryParams = append(queryParams, "otoco_config[]=post_only:true")
-			}
-			if config.RejectPostOnly {
-				queryParams = append(queryParams, "otoco_config[]=reject_post_only:true")
-			}
-			if config.TriggerPrice != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=trigger_price:%f", config.TriggerPrice))
-			}
-			if config.TriggerOffset != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=trigger_offset:%f", config.TriggerOffset))
-			}
-			if config.Trigger != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=trigger:%s", config.Trigger))
-			}
+// ## Send order with the transport of the client after validation
+func (s *OrderService) placeOrder(ctx context.Context, urlPath string, request *OrderRequest, opts ...CallOption) (*OrderResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if s.client.instruments != nil {
+		if err := s.client.instruments.NormalizeOrder(ctx, request, s.client.normalizePolicy); err != nil {
+			return nil, err
 		}
 	}
 
-	uri += "?" + strings.Join(queryParams, "&")
-
-	// ## [DEBUG]
-	// fmt.Printf("buy uri: %s \n", uri)
+	q, err := orderQuery(request)
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	var resp OrderResponse
+	err = s.client.request(ctx, urlPath, q, &resp, s.orderCallOptions(ctx, request, opts)...)
 	if err != nil {
 		return nil, err
 	}
 	return &resp, nil
 }
 
-// ## Create PostBuy Order
-func (s *OrderService) PostBuy(
-	instrumentName string,
-	amount float64,
-	contracts int64,
-	orderType string,
-	label string,
-	price float64,
-	timeInForce string,
-	maxShow int64,
-	postOnly bool,
-	rejectPostOnly bool,
-	reduceOnly bool,
-	triggerPrice float64,
-	triggerOffset float64,
-	trigger string,
-	advanced string,
-	mmp bool,
-	validUntil int64,
-	linkedOrderType string,
-	triggerFillCondition string,
-	otocoConfig []OTOCOConfig,
-) (*OrderResponse, error) {
+// ## Send order with POST JSON-RPC body after validation, also with WithGETTransport
+func (s *OrderService) postOrder(ctx context.Context, urlPath string, request *OrderRequest, opts ...CallOption) (*OrderResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if s.client.instruments != nil {
+		if err := s.client.instruments.NormalizeOrder(ctx, request, s.client.normalizePolicy); err != nil {
+			return nil, err
+		}
+	}
+
 	var resp OrderResponse
-	uri := fmt.Sprintf("%s%s%s", s.client.baseURL, defaultAPIURL, urlPathBuy)
-
-	orderRequest := OrderRequest{
-		InstrumentName:       instrumentName,
-		Amount:               amount,
-		Contracts:            contracts,
-		Type:                 orderType,
-		Label:                label,
-		Price:                price,
-		TimeInForce:          timeInForce,
-		MaxShow:              maxShow,
-		PostOnly:             postOnly,
-		RejectPostOnly:       rejectPostOnly,
-		ReduceOnly:           reduceOnly,
-		TriggerPrice:         triggerPrice,
-		TriggerOffset:        triggerOffset,
-		Trigger:              trigger,
-		Advanced:             advanced,
-		MMP:                  mmp,
-		ValidUntil:           validUntil,
-		LinkedOrderType:      linkedOrderType,
-		TriggerFillCondition: triggerFillCondition,
-		OTOCOConfig:          otocoConfig,
-	}
-
-	orderRequestBody := OrderRequestBody{
-		Method:  strings.TrimPrefix(urlPathBuy, "/"),
-		JSONRPC: "2.0",
-		Params:  orderRequest,
-	}
-
-	err := s.client.DoPrivate(uri, "POST", orderRequestBody, &resp)
+	err := s.client.call(ctx, strings.TrimPrefix(urlPath, "/"), request, &resp, s.orderCallOptions(ctx, request, opts)...)
 	if err != nil {
 		return nil, err
 	}
 	return &resp, nil
 }
 
+// ## Create Buy Order
+func (s *OrderService) Buy(ctx context.Context, request *OrderRequest, opts ...CallOption) (*OrderResponse, error) {
+	return s.placeOrder(ctx, urlPathBuy, request, opts...)
+}
+
+// ## Create PostBuy Order
+func (s *OrderService) PostBuy(ctx context.Context, request *OrderRequest, opts ...CallOption) (*OrderResponse, error) {
+	return s.postOrder(ctx, urlPathBuy, request, opts...)
+}
+
 // ## Create Sell Order
-func (s *OrderService) Sell(
-	instrumentName string,
-	amount float64,
-	contracts int64,
-	orderType string,
-	label string,
-	price float64,
-	timeInForce string,
-	maxShow int64,
-	postOnly bool,
-	rejectPostOnly bool,
-	reduceOnly bool,
-	triggerPrice float64,
-	triggerOffset float64,
-	trigger string,
-	advanced string,
-	mmp bool,
-	validUntil int64,
-	linkedOrderType string,
-	triggerFillCondition string,
-	otocoConfig []OTOCOConfig,
-) (*OrderResponse, error) {
-	var resp OrderResponse
-	uri := fmt.Sprintf("%s%s%s", s.client.baseURL, defaultAPIURL, urlPathSell)
-	queryParams := make([]string, 0, 20)
+func (s *OrderService) Sell(ctx context.Context, request *OrderRequest, opts ...CallOption) (*OrderResponse, error) {
+	return s.placeOrder(ctx, urlPathSell, request, opts...)
+}
 
-	if instrumentName != "" {
-		queryParams = append(queryParams, fmt.Sprintf("instrument_name=%s", instrumentName))
-	}
-	if amount != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("amount=%f", amount))
-	}
-	if contracts != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("contracts=%d", contracts))
-	}
-	if orderType != "" {
-		queryParams = append(queryParams, fmt.Sprintf("type=%s", orderType))
-	}
-	if label != "" {
-		queryParams = append(queryParams, fmt.Sprintf("label=%s", label))
-	}
-	if price != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("price=%f", price))
-	}
-	if timeInForce != "" {
-		queryParams = append(queryParams, fmt.Sprintf("time_in_force=%s", timeInForce))
-	}
-	if maxShow != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("max_show=%d", maxShow))
-	}
-	if postOnly {
-		queryParams = append(queryParams, "post_only=true")
-	}
-	if rejectPostOnly {
-		queryParams = append(queryParams, "reject_post_only=true")
-	}
-	if reduceOnly {
-		queryParams = append(queryParams, "reduce_only=true")
-	}
-	if triggerPrice != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("trigger_price=%f", triggerPrice))
-	}
-	if triggerOffset != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("trigger_offset=%f", triggerOffset))
-	}
-	if trigger != "" {
-		queryParams = append(queryParams, fmt.Sprintf("trigger=%s", trigger))
-	}
-	if advanced != "" {
-		queryParams = append(queryParams, fmt.Sprintf("advanced=%s", advanced))
-	}
-	if mmp {
-		queryParams = append(queryParams, "mmp=true")
-	}
-	if validUntil != 0 {
-		queryParams = append(queryParams, fmt.Sprintf("valid_until=%d", validUntil))
+// ## Create PostSell Order
+func (s *OrderService) PostSell(ctx context.Context, request *OrderRequest, opts ...CallOption) (*OrderResponse, error) {
+	return s.postOrder(ctx, urlPathSell, request, opts...)
+}
+
+// ## Edit Order By ID
+func (s *OrderService) Edit(ctx context.Context, request *EditRequest, opts ...CallOption) (*OrderResponse, error) {
+	if request.OrderID == "" {
+		return nil, fmt.Errorf("%w: order_id is required", ErrInvalidOrder)
 	}
-	if linkedOrderType != "" {
-		queryParams = append(queryParams, fmt.Sprintf("linked_order_type=%s", linkedOrderType))
+	return s.editOrder(ctx, urlPathEdit, request, opts...)
+}
+
+// ## Edit Order By Label, the label must match exactly one open order of the instrument
+func (s *OrderService) EditByLabel(ctx context.Context, request *EditRequest, opts ...CallOption) (*OrderResponse, error) {
+	if request.Label == "" || request.InstrumentName == "" {
+		return nil, fmt.Errorf("%w: label and instrument_name are required", ErrInvalidOrder)
 	}
-	if triggerFillCondition != "" {
-		queryParams = append(queryParams, fmt.Sprintf("trigger_fill_condition=%s", triggerFillCondition))
+	if request.OrderID != "" {
+		return nil, fmt.Errorf("%w: order_id is not allowed with edit by label", ErrInvalidOrder)
 	}
+	return s.editOrder(ctx, urlPathEditByLabel, request, opts...)
+}
 
-	if len(otocoConfig) > 0 {
-		for _, config := range otocoConfig {
-			if config.Amount != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=amount:%f", config.Amount))
-			}
-			if config.Direction != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=direction:%s", config.Direction))
-			}
-			if config.Type != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=type:%s", config.Type))
-			}
-			if config.Label != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=label:%s", config.Label))
-			}
-			if config.Price != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=price:%f", config.Price))
-			}
-			if config.ReduceOnly {
-				queryParams = append(queryParams, "otoco_config[]=reduce_only:true")
-			}
-			if config.TimeInForce != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=time_in_force:%s", config.TimeInForce))
-			}
-			if config.PostOnly {
-				queryParams = append(queryParams, "otoco_config[]=post_only:true")
-			}
-			if config.RejectPostOnly {
-				queryParams = append(queryParams, "otoco_config[]=reject_post_only:true")
-			}
-			if config.TriggerPrice != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=trigger_price:%f", config.TriggerPrice))
-			}
-			if config.TriggerOffset != 0 {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=trigger_offset:%f", config.TriggerOffset))
-			}
-			if config.Trigger != "" {
-				queryParams = append(queryParams, fmt.Sprintf("otoco_config[]=trigger:%s", config.Trigger))
+func (s *OrderService) editOrder(ctx context.Context, urlPath string, request *EditRequest, opts ...CallOption) (*OrderResponse, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	if s.client.instruments != nil {
+		// ## An edit by order id has no instrument name, it is looked up with the credentials of this client
+		instrumentName := request.InstrumentName
+		if instrumentName == "" {
+			var err error
+			if instrumentName, err = orderInstrument(ctx, s, request.OrderID); err != nil {
+				return nil, err
 			}
 		}
+		if err := s.client.instruments.normalizeEdit(ctx, instrumentName, request, s.client.normalizePolicy); err != nil {
+			return nil, err
+		}
 	}
 
-	uri += "?" + strings.Join(queryParams, "&")
-
-	// ## [DEBUG]
-	// fmt.Printf("Sell uri: %s \n", uri)
-
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
-	if err != nil {
-		return nil, err
-	}
-	return &resp, nil
-}
-
-// ## Create PostSell Order
-func (s *OrderService) PostSell(
-	instrumentName string,
-	amount float64,
-	contracts int64,
-	orderType string,
-	label string,
-	price float64,
-	timeInForce string,
-	maxShow int64,
-	postOnly bool,
-	rejectPostOnly bool,
-	reduceOnly bool,
-	triggerPrice float64,
-	triggerOffset float64,
-	trigger string,
-	advanced string,
-	mmp bool,
-	validUntil int64,
-	linkedOrderType string,
-	triggerFillCondition string,
-	otocoConfig []OTOCOConfig,
-) (*OrderResponse, error) {
 	var resp OrderResponse
-	uri := fmt.Sprintf("%s%s%s", s.client.baseURL, defaultAPIURL, urlPathSell)
-
-	orderRequest := OrderRequest{
-		InstrumentName:       instrumentName,
-		Amount:               amount,
-		Contracts:            contracts,
-		Type:                 orderType,
-		Label:                label,
-		Price:                price,
-		TimeInForce:          timeInForce,
-		MaxShow:              maxShow,
-		PostOnly:             postOnly,
-		RejectPostOnly:       rejectPostOnly,
-		ReduceOnly:           reduceOnly,
-		TriggerPrice:         triggerPrice,
-		TriggerOffset:        triggerOffset,
-		Trigger:              trigger,
-		Advanced:             advanced,
-		MMP:                  mmp,
-		ValidUntil:           validUntil,
-		LinkedOrderType:      linkedOrderType,
-		TriggerFillCondition: triggerFillCondition,
-		OTOCOConfig:          otocoConfig,
-	}
-
-	orderRequestBody := OrderRequestBody{
-		Method:  strings.TrimPrefix(urlPathSell, "/"),
-		JSONRPC: "2.0",
-		Params:  orderRequest,
-	}
-
-	err := s.client.DoPrivate(uri, "POST", orderRequestBody, &resp)
+	err := s.client.request(ctx, urlPath, editQuery(request), &resp, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -713,15 +390,10 @@ func (s *OrderService) PostSell(
 }
 
 // ## Cancel One Order By ID
-func (s *OrderService) Cancel(orderID string) (*OrderResponse, error) {
+func (s *OrderService) Cancel(ctx context.Context, orderID string) (*OrderResponse, error) {
 	var resp OrderResponse
-	uri := fmt.Sprintf("%s%s%s?order_id=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathCancelOneOrder,
-		orderID,
-	)
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	q := newQuery().set("order_id", orderID)
+	err := s.client.request(ctx, urlPathCancelOneOrder, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -729,10 +401,9 @@ func (s *OrderService) Cancel(orderID string) (*OrderResponse, error) {
 }
 
 // ## Cancel All Open Order
-func (s *OrderService) CancelAll() (*CancelAllResponse, error) {
+func (s *OrderService) CancelAll(ctx context.Context) (*CancelAllResponse, error) {
 	var resp CancelAllResponse
-	uri := fmt.Sprintf("%s%s%s", s.client.baseURL, defaultAPIURL, urlPathCancelAllOrder)
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathCancelAllOrder, nil, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -741,6 +412,7 @@ func (s *OrderService) CancelAll() (*CancelAllResponse, error) {
 
 // ## Cancel All By Instrument
 func (s *OrderService) CancelAllByInstrument(
+	ctx context.Context,
 	instrumentName string,
 	orderType string,
 	detailed bool,
@@ -748,28 +420,147 @@ func (s *OrderService) CancelAllByInstrument(
 	freezeQuotes bool,
 ) (*CancelAllByInstrumentResponse, error) {
 	var resp CancelAllByInstrumentResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathCancelAllByInstrument,
-		instrumentName,
-	)
+	q := newQuery().set("instrument_name", instrumentName)
 
 	if orderType != "" {
-		uri += fmt.Sprintf("&type=%s", orderType)
+		q.set("type", orderType)
 	}
 	if detailed {
-		uri += "&detailed=true"
+		q.set("detailed", true)
 	}
 	if includeCombos {
-		uri += "&include_combos=true"
+		q.set("include_combos", true)
+	}
+	if freezeQuotes {
+		q.set("freeze_quotes", true)
+	}
+
+	err := s.client.request(ctx, urlPathCancelAllByInstrument, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Cancel Orders By Label, currency is optional
+func (s *OrderService) CancelByLabel(ctx context.Context, label string, currency string) (*CancelAllResponse, error) {
+	var resp CancelAllResponse
+	q := newQuery().set("label", label)
+
+	if currency != "" {
+		q.set("currency", currency)
+	}
+
+	err := s.client.request(ctx, urlPathCancelByLabel, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Cancel All By Currency, kind and orderType are optional
+func (s *OrderService) CancelAllByCurrency(
+	ctx context.Context,
+	currency string,
+	kind string,
+	orderType string,
+	detailed bool,
+	freezeQuotes bool,
+) (*CancelResponse, error) {
+	var resp CancelResponse
+	q := newQuery().set("currency", currency)
+
+	if kind != "" {
+		q.set("kind", kind)
+	}
+	if orderType != "" {
+		q.set("type", orderType)
+	}
+	if detailed {
+		q.set("detailed", true)
+	}
+	if freezeQuotes {
+		q.set("freeze_quotes", true)
+	}
+
+	err := s.client.request(ctx, urlPathCancelAllByCurrency, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Cancel All By Kind Or Type, currencies may be "any", kinds and orderTypes are optional
+func (s *OrderService) CancelAllByKindOrType(
+	ctx context.Context,
+	currencies []string,
+	kinds []string,
+	orderTypes []string,
+	detailed bool,
+	freezeQuotes bool,
+) (*CancelResponse, error) {
+	var resp CancelResponse
+	q := newQuery()
+
+	if err := q.setStringOrArray("currency", currencies); err != nil {
+		return nil, err
+	}
+	if len(kinds) > 0 {
+		if err := q.setStringOrArray("kind", kinds); err != nil {
+			return nil, err
+		}
+	}
+	if len(orderTypes) > 0 {
+		if err := q.setStringOrArray("type", orderTypes); err != nil {
+			return nil, err
+		}
+	}
+	if detailed {
+		q.set("detailed", true)
 	}
 	if freezeQuotes {
-		uri += "&freeze_quotes=true"
+		q.set("freeze_quotes", true)
 	}
 
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathCancelAllByKindOrType, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Cancel Quotes
+func (s *OrderService) CancelQuotes(ctx context.Context, request *CancelQuotesRequest) (*CancelResponse, error) {
+	var resp CancelResponse
+	q := newQuery().set("cancel_type", request.CancelType)
+
+	switch request.CancelType {
+	case "delta":
+		q.set("min_delta", request.MinDelta).set("max_delta", request.MaxDelta)
+	case "quote_set_id":
+		q.set("quote_set_id", request.QuoteSetID)
+	case "instrument":
+		q.set("instrument_name", request.InstrumentName)
+	case "instrument_kind":
+		q.set("kind_name", request.Kind)
+	case "currency":
+		q.set("currency", request.Currency)
+	case "all":
+	default:
+		return nil, fmt.Errorf("unknown cancel_type %q", request.CancelType)
+	}
+
+	if request.CancelType != "currency" && request.Currency != "" {
+		q.set("currency", request.Currency)
+	}
+	if request.Detailed {
+		q.set("detailed", true)
+	}
+	if request.FreezeQuotes {
+		q.set("freeze_quotes", true)
+	}
+
+	err := s.client.request(ctx, urlPathCancelQuotes, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -777,16 +568,10 @@ func (s *OrderService) CancelAllByInstrument(
 }
 
 // ## Get Order State by order_id
-func (s *OrderService) GetOrderState(orderID string) (*GetOrderStateResponse, error) {
+func (s *OrderService) GetOrderState(ctx context.Context, orderID string) (*GetOrderStateResponse, error) {
 	var resp GetOrderStateResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?order_id=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOrderState,
-		orderID,
-	)
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	q := newQuery().set("order_id", orderID)
+	err := s.client.request(ctx, urlPathGetOrderState, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -794,17 +579,12 @@ func (s *OrderService) GetOrderState(orderID string) (*GetOrderStateResponse, er
 }
 
 // ## Get Order State by order label from api
-func (s *OrderService) GetOrderStateByLabel(currency, label string) (*GetOrderStateByLabelResponse, error) {
+func (s *OrderService) GetOrderStateByLabel(ctx context.Context, currency, label string) (*GetOrderStateByLabelResponse, error) {
 	var resp GetOrderStateByLabelResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?currency=%s&label=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOrderStateByLabel,
-		currency,
-		label,
-	)
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("currency", currency).
+		set("label", label)
+	err := s.client.request(ctx, urlPathGetOrderStateByLabel, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -812,27 +592,13 @@ func (s *OrderService) GetOrderStateByLabel(currency, label string) (*GetOrderSt
 }
 
 // ## Get Open Orders
-func (s *OrderService) GetOpenOrders(kind, orderType string) (*GetOpenOrdersResponse, error) {
+func (s *OrderService) GetOpenOrders(ctx context.Context, kind, orderType string) (*GetOpenOrdersResponse, error) {
 	var resp GetOpenOrdersResponse
-	uri := fmt.Sprintf("%s%s%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOpenOrders,
-	)
+	q := newQuery().
+		setOptional("kind", kind).
+		setOptional("type", orderType)
 
-	queryParams := make([]string, 0, 2)
-	if kind != "" {
-		queryParams = append(queryParams, fmt.Sprintf("kind=%s", kind))
-	}
-	if orderType != "" {
-		queryParams = append(queryParams, fmt.Sprintf("type=%s", orderType))
-	}
-
-	if len(queryParams) > 0 {
-		uri += "?" + strings.Join(queryParams, "&")
-	}
-
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetOpenOrders, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -840,21 +606,15 @@ func (s *OrderService) GetOpenOrders(kind, orderType string) (*GetOpenOrdersResp
 }
 
 // ## Get Open Orders by Instrument
-func (s *OrderService) GetOpenOrdersByInstrument(instrumentName, orderType string) (*GetOpenOrdersByInstrumentResponse, error) {
+func (s *OrderService) GetOpenOrdersByInstrument(ctx context.Context, instrumentName, orderType string) (*GetOpenOrdersByInstrumentResponse, error) {
 	var resp GetOpenOrdersByInstrumentResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOpenOrdersByInstrument,
-		instrumentName,
-	)
+	q := newQuery().set("instrument_name", instrumentName)
 
 	if orderType != "" {
-		uri += fmt.Sprintf("&type=%s", orderType)
+		q.set("type", orderType)
 	}
 
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetOpenOrdersByInstrument, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -863,6 +623,7 @@ func (s *OrderService) GetOpenOrdersByInstrument(instrumentName, orderType strin
 
 // ## Get History Orders By Currency
 func (s *OrderService) GetOrderHistoryByCurrency(
+	ctx context.Context,
 	currency string,
 	kind string,
 	count int,
@@ -871,31 +632,25 @@ func (s *OrderService) GetOrderHistoryByCurrency(
 	includeUnfilled bool,
 ) (*GetOrderHistoryByCurrencyResponse, error) {
 	var resp GetOrderHistoryByCurrencyResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?currency=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOrderHistoryByCurrency,
-		currency,
-	)
+	q := newQuery().set("currency", currency)
 
 	if kind != "" {
-		uri += fmt.Sprintf("&kind=%s", kind)
+		q.set("kind", kind)
 	}
 	if count > 0 {
-		uri += fmt.Sprintf("&count=%d", count)
+		q.set("count", count)
 	}
 	if offset > 0 {
-		uri += fmt.Sprintf("&offset=%d", offset)
+		q.set("offset", offset)
 	}
 	if includeOld {
-		uri += "&include_old=true"
+		q.set("include_old", true)
 	}
 	if includeUnfilled {
-		uri += "&include_unfilled=true"
+		q.set("include_unfilled", true)
 	}
 
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetOrderHistoryByCurrency, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -904,6 +659,7 @@ func (s *OrderService) GetOrderHistoryByCurrency(
 
 // ## Get Order History By Instrument
 func (s *OrderService) GetOrderHistoryByInstrument(
+	ctx context.Context,
 	instrumentName string,
 	count int,
 	offset int,
@@ -911,28 +667,22 @@ func (s *OrderService) GetOrderHistoryByInstrument(
 	includeUnfilled bool,
 ) (*GetOrderHistoryByInstrumentResponse, error) {
 	var resp GetOrderHistoryByInstrumentResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetOrderHistoryByInstrument,
-		instrumentName,
-	)
+	q := newQuery().set("instrument_name", instrumentName)
 
 	if count > 0 {
-		uri += fmt.Sprintf("&count=%d", count)
+		q.set("count", count)
 	}
 	if offset > 0 {
-		uri += fmt.Sprintf("&offset=%d", offset)
+		q.set("offset", offset)
 	}
 	if includeOld {
-		uri += "&include_old=true"
+		q.set("include_old", true)
 	}
 	if includeUnfilled {
-		uri += "&include_unfilled=true"
+		q.set("include_unfilled", true)
 	}
 
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetOrderHistoryByInstrument, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -941,31 +691,26 @@ func (s *OrderService) GetOrderHistoryByInstrument(
 
 // ## Get Trigger Order History
 func (s *OrderService) GetTriggerOrderHistory(
+	ctx context.Context,
 	currency string,
 	instrumentName string,
 	count int,
 	continuation string,
 ) (*GetTriggerOrderHistoryResponse, error) {
 	var resp GetTriggerOrderHistoryResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?currency=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetTriggerOrderHistory,
-		currency,
-	)
+	q := newQuery().set("currency", currency)
 
 	if instrumentName != "" {
-		uri += fmt.Sprintf("&instrument_name=%s", instrumentName)
+		q.set("instrument_name", instrumentName)
 	}
 	if count > 0 {
-		uri += fmt.Sprintf("&count=%d", count)
+		q.set("count", count)
 	}
 	if continuation != "" {
-		uri += fmt.Sprintf("&continuation=%s", continuation)
+		q.set("continuation", continuation)
 	}
 
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetTriggerOrderHistory, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -987,22 +732,18 @@ type GetMarginsResponse struct {
 
 // ## Get Margins
 func (s *OrderService) GetMargins(
+	ctx context.Context,
 	instrumentName string,
 	amount float64,
 	price float64,
 ) (*GetMarginsResponse, error) {
 	var resp GetMarginsResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s&amount=%f&price=%f",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetMargins,
-		instrumentName,
-		amount,
-		price,
-	)
-
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		set("amount", amount).
+		set("price", price)
+
+	err := s.client.request(ctx, urlPathGetMargins, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bitbucket.org/ohm89/go-deribit/deribit"
+)
+
+// ErrInvalidOrder is wrapped by every validation error of OrderRequest
+var ErrInvalidOrder = deribit.ErrInvalidOrder
+
+const (
+	OrderTypeLimit        = deribit.OrderTypeLimit
+	OrderTypeMarket       = deribit.OrderTypeMarket
+	OrderTypeStopLimit    = deribit.OrderTypeStopLimit
+	OrderTypeStopMarket   = deribit.OrderTypeStopMarket
+	OrderTypeTakeLimit    = deribit.OrderTypeTakeLimit
+	OrderTypeTakeMarket   = deribit.OrderTypeTakeMarket
+	OrderTypeMarketLimit  = deribit.OrderTypeMarketLimit
+	OrderTypeTrailingStop = deribit.OrderTypeTrailingStop
+
+	TimeInForceGoodTilCancelled  = deribit.TimeInForceGoodTilCancelled
+	TimeInForceGoodTilDay        = deribit.TimeInForceGoodTilDay
+	TimeInForceFillOrKill        = deribit.TimeInForceFillOrKill
+	TimeInForceImmediateOrCancel = deribit.TimeInForceImmediateOrCancel
+
+	LinkedOrderTypeOTO   = deribit.LinkedOrderTypeOTO
+	LinkedOrderTypeOCO   = deribit.LinkedOrderTypeOCO
+	LinkedOrderTypeOTOCO = deribit.LinkedOrderTypeOTOCO
+)
+
+// ## ------------------------------------------------------------------------
+
+// OrderBuilder builds an OrderRequest step by step, e.g. NewLimitOrder(inst).Amount(x).Price(p).PostOnly()
+type OrderBuilder struct {
+	request OrderRequest
+}
+
+func NewOrder(instrumentName string, orderType string) *OrderBuilder {
+	return &OrderBuilder{
+		request: OrderRequest{
+			InstrumentName: instrumentName,
+			Type:           orderType,
+		},
+	}
+}
+
+func NewLimitOrder(instrumentName string) *OrderBuilder {
+	return NewOrder(instrumentName, OrderTypeLimit)
+}
+
+func NewMarketOrder(instrumentName string) *OrderBuilder {
+	return NewOrder(instrumentName, OrderTypeMarket)
+}
+
+func NewStopLimitOrder(instrumentName string, triggerPrice float64, trigger string) *OrderBuilder {
+	return NewOrder(instrumentName, OrderTypeStopLimit).TriggerPrice(triggerPrice).Trigger(trigger)
+}
+
+func NewStopMarketOrder(instrumentName string, triggerPrice float64, trigger string) *OrderBuilder {
+	return NewOrder(instrumentName, OrderTypeStopMarket).TriggerPrice(triggerPrice).Trigger(trigger)
+}
+
+func NewTakeLimitOrder(instrumentName string, triggerPrice float64, trigger string) *OrderBuilder {
+	return NewOrder(instrumentName, OrderTypeTakeLimit).TriggerPrice(triggerPrice).Trigger(trigger)
+}
+
+func NewTakeMarketOrder(instrumentName string, triggerPrice float64, trigger string) *OrderBuilder {
+	return NewOrder(instrumentName, OrderTypeTakeMarket).TriggerPrice(triggerPrice).Trigger(trigger)
+}
+
+func NewTrailingStopOrder(instrumentName string, triggerOffset float64, trigger string) *OrderBuilder {
+	return NewOrder(instrumentName, OrderTypeTrailingStop).TriggerOffset(triggerOffset).Trigger(trigger)
+}
+
+func (b *OrderBuilder) Amount(amount float64) *OrderBuilder {
+	b.request.Amount = Decimal(amount)
+	return b
+}
+
+func (b *OrderBuilder) Contracts(contracts int64) *OrderBuilder {
+	b.request.Contracts = contracts
+	return b
+}
+
+func (b *OrderBuilder) Label(label string) *OrderBuilder {
+	b.request.Label = label
+	return b
+}
+
+func (b *OrderBuilder) Price(price float64) *OrderBuilder {
+	b.request.Price = NewDecimal(price)
+	return b
+}
+
+func (b *OrderBuilder) TimeInForce(timeInForce string) *OrderBuilder {
+	b.request.TimeInForce = timeInForce
+	return b
+}
+
+func (b *OrderBuilder) MaxShow(maxShow int64) *OrderBuilder {
+	b.request.MaxShow = maxShow
+	return b
+}
+
+func (b *OrderBuilder) PostOnly() *OrderBuilder {
+	b.request.PostOnly = true
+	return b
+}
+
+// ## RejectPostOnly rejects instead of repricing a post only order that would take liquidity
+func (b *OrderBuilder) RejectPostOnly() *OrderBuilder {
+	b.request.PostOnly = true
+	b.request.RejectPostOnly = true
+	return b
+}
+
+func (b *OrderBuilder) ReduceOnly() *OrderBuilder {
+	b.request.ReduceOnly = true
+	return b
+}
+
+func (b *OrderBuilder) TriggerPrice(triggerPrice float64) *OrderBuilder {
+	b.request.TriggerPrice = Decimal(triggerPrice)
+	return b
+}
+
+func (b *OrderBuilder) TriggerOffset(triggerOffset float64) *OrderBuilder {
+	b.request.TriggerOffset = Decimal(triggerOffset)
+	return b
+}
+
+func (b *OrderBuilder) Trigger(trigger string) *OrderBuilder {
+	b.request.Trigger = trigger
+	return b
+}
+
+func (b *OrderBuilder) Advanced(advanced string) *OrderBuilder {
+	b.request.Advanced = advanced
+	return b
+}
+
+func (b *OrderBuilder) MMP() *OrderBuilder {
+	b.request.MMP = true
+	return b
+}
+
+func (b *OrderBuilder) ValidUntil(validUntil int64) *OrderBuilder {
+	b.request.ValidUntil = validUntil
+	return b
+}
+
+// ## Linked sets linked_order_type with its otoco_config orders
+func (b *OrderBuilder) Linked(linkedOrderType string, otocoConfig ...OTOCOConfig) *OrderBuilder {
+	b.request.LinkedOrderType = linkedOrderType
+	b.request.OTOCOConfig = append(b.request.OTOCOConfig, otocoConfig...)
+	return b
+}
+
+func (b *OrderBuilder) TriggerFillCondition(triggerFillCondition string) *OrderBuilder {
+	b.request.TriggerFillCondition = triggerFillCondition
+	return b
+}
+
+// ## Build validates and returns a copy of the request
+func (b *OrderBuilder) Build() (*OrderRequest, error) {
+	request := b.request
+	request.OTOCOConfig = append([]OTOCOConfig(nil), b.request.OTOCOConfig...)
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
@@ -1,10 +1,9 @@
 package api
 
 import (
-	"encoding/json"
-	"fmt"
-	"net/url"
-	"strings"
+	"context"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
 )
 
 type PositionService struct {
@@ -24,48 +23,18 @@ type GetPositionDetailsResponse struct {
 	Result  Position `json:"result"`
 }
 
-type Position struct {
-	AveragePrice              float64 `json:"average_price"`
-	AveragePriceUSD           float64 `json:"average_price_usd"`
-	Delta                     float64 `json:"delta"`
-	Direction                 string  `json:"direction"`
-	EstimatedLiquidationPrice float64 `json:"estimated_liquidation_price"`
-	FloatingProfitLoss        float64 `json:"floating_profit_loss"`
-	FloatingProfitLossUSD     float64 `json:"floating_profit_loss_usd"`
-	Gamma                     float64 `json:"gamma"`
-	IndexPrice                float64 `json:"index_price"`
-	InitialMargin             float64 `json:"initial_margin"`
-	InstrumentName            string  `json:"instrument_name"`
-	InterestValue             float64 `json:"interest_value"`
-	Kind                      string  `json:"kind"`
-	Leverage                  int     `json:"leverage"`
-	MaintenanceMargin         float64 `json:"maintenance_margin"`
-	MarkPrice                 float64 `json:"mark_price"`
-	OpenOrdersMargin          float64 `json:"open_orders_margin"`
-	RealizedFunding           float64 `json:"realized_funding"`
-	RealizedProfitLoss        float64 `json:"realized_profit_loss"`
-	SettlementPrice           float64 `json:"settlement_price"`
-	Size                      float64 `json:"size"`
-	SizeCurrency              float64 `json:"size_currency"`
-	Theta                     float64 `json:"theta"`
-	TotalProfitLoss           float64 `json:"total_profit_loss"`
-	Vega                      float64 `json:"vega"`
-}
+// Position is deribit.Position, shared with the ws client
+type Position = deribit.Position
 
 // ## Get Position
 func (s *PositionService) GetPosition(
+	ctx context.Context,
 	instrumentName string,
 ) (*GetPositionDetailsResponse, error) {
 	var resp GetPositionDetailsResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathGetPosition,
-		instrumentName,
-	)
-
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	q := newQuery().set("instrument_name", instrumentName)
+
+	err := s.client.request(ctx, urlPathGetPosition, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -79,29 +48,21 @@ type GetPositionsResponse struct {
 }
 
 func (s *PositionService) GetPositions(
+	ctx context.Context,
 	currency string,
 	kind string,
 	subaccountID int,
 ) (*GetPositionsResponse, error) {
 	var resp GetPositionsResponse
-	uri := fmt.Sprintf("%s%s%s", s.client.baseURL, defaultAPIURL, urlPathGetPositions)
-	queryParams := make([]string, 0, 3)
+	q := newQuery().
+		setOptional("currency", currency).
+		setOptional("kind", kind)
 
-	if currency != "" {
-		queryParams = append(queryParams, fmt.Sprintf("currency=%s", currency))
-	}
-	if kind != "" {
-		queryParams = append(queryParams, fmt.Sprintf("kind=%s", kind))
-	}
 	if subaccountID > 0 {
-		queryParams = append(queryParams, fmt.Sprintf("subaccount_id=%d", subaccountID))
+		q.set("subaccount_id", subaccountID)
 	}
 
-	if len(queryParams) > 0 {
-		uri += "?" + strings.Join(queryParams, "&")
-	}
-
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	err := s.client.request(ctx, urlPathGetPositions, q, &resp)
 	if err != nil {
 		return nil, err
 	}
@@ -168,44 +129,49 @@ type SimulatePortfolioResponse struct {
 
 // ## Get Simulated Margins
 func (s *PositionService) GetSimulateMargins(
+	ctx context.Context,
 	currency string,
 	addPositions bool,
 	simulatedPositions map[string]float64,
 ) (*SimulatePortfolioResponse, error) {
 	var resp SimulatePortfolioResponse
-	uri := fmt.Sprintf("%s%s%s", s.client.baseURL, defaultAPIURL, urlPathGetSimulateMargins)
-	queryParams := make([]string, 0, 3)
-
-	queryParams = append(queryParams, fmt.Sprintf("currency=%s", currency))
-	if addPositions {
-		queryParams = append(queryParams, "add_positions=true")
-	} else {
-		queryParams = append(queryParams, "add_positions=false")
+	q, err := simulateMarginsQuery(currency, addPositions, simulatedPositions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.client.request(ctx, urlPathGetSimulateMargins, q, &resp)
+	if err != nil {
+		return nil, err
 	}
+	return &resp, nil
+}
+
+// ## simulateMarginsQuery holds the params of GetSimulateMargins, simulated_positions is one JSON object
+func simulateMarginsQuery(currency string, addPositions bool, simulatedPositions map[string]float64) (*query, error) {
+	q := newQuery().
+		set("currency", currency).
+		set("add_positions", addPositions)
 
 	if len(simulatedPositions) > 0 {
-		// Encode the simulated positions map as a JSON string
-		simulatedPositionsJSON, err := json.Marshal(simulatedPositions)
-		if err != nil {
+		// Encode the simulated positions map as a JSON object, with the sizes as Decimal
+		positions := make(map[string]Decimal, len(simulatedPositions))
+		for instrumentName, size := range simulatedPositions {
+			positions[instrumentName] = Decimal(size)
+		}
+		if err := q.setJSON("simulated_positions", positions); err != nil {
 			return nil, err
 		}
-		queryParams = append(queryParams, fmt.Sprintf("simulated_positions=%s", url.QueryEscape(string(simulatedPositionsJSON))))
 	}
 
-	uri += "?" + strings.Join(queryParams, "&")
-
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
-	if err != nil {
-		return nil, err
-	}
-	return &resp, nil
+	return q, nil
 }
 
 // ## ---------------------------------------
 type ClosePositionRequest struct {
 	InstrumentName string  `json:"instrument_name"`
 	Type           string  `json:"type"`
-	Price          float64 `json:"price,omitempty"`
+	Price          Decimal `json:"price,omitempty"`
 }
 
 type ClosePositionResponse struct {
@@ -221,21 +187,17 @@ type ClosePosition struct {
 
 // ## Close Position
 func (s *PositionService) ClosePosition(
+	ctx context.Context,
 	instrumentName string,
 	orderType string,
 	price float64,
 ) (*ClosePositionResponse, error) {
 	var resp ClosePositionResponse
-	uri := fmt.Sprintf(
-		"%s%s%s?instrument_name=%s&type=%s&price=%f",
-		s.client.baseURL,
-		defaultAPIURL,
-		urlPathClosePosition,
-		instrumentName,
-		orderType,
-		price,
-	)
-	err := s.client.DoPrivate(uri, "GET", nil, &resp)
+	q := newQuery().
+		set("instrument_name", instrumentName).
+		set("type", orderType).
+		set("price", price)
+	err := s.client.request(ctx, urlPathClosePosition, q, &resp)
 	if err != nil {
 		return nil, err
 	}
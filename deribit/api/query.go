@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// ## Parameters of the REST endpoints, sent as JSON-RPC params or as a GET query string escaped by url.Values
+
+// ## query holds the parameters of a request. set always sends a parameter, setOptional leaves out a zero value
+// and setJSON sends arrays and objects as JSON params, or as one JSON parameter the way Deribit reads them from a GET.
+type query struct {
+	params map[string]any
+	values url.Values
+}
+
+func newQuery() *query {
+	return &query{
+		params: make(map[string]any),
+		values: make(url.Values),
+	}
+}
+
+func (q *query) set(key string, value any) *query {
+	if v, ok := value.(float64); ok {
+		// ## Decimal keeps the JSON of a float without exponent
+		value = Decimal(v)
+	}
+	if v, ok := value.(*Decimal); ok {
+		// ## An optional decimal is sent when set, even when it is zero
+		value = *v
+	}
+	q.params[key] = value
+	q.values.Set(key, formatParam(value))
+	return q
+}
+
+func (q *query) setOptional(key string, value any) *query {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		return q
+	}
+	return q.set(key, value)
+}
+
+func (q *query) setJSON(key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	q.params[key] = value
+	q.values.Set(key, string(data))
+	return nil
+}
+
+// ## setStringOrArray sends one value as is and several as a JSON array
+func (q *query) setStringOrArray(key string, values []string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("%s: at least one value is required", key)
+	}
+	if len(values) == 1 {
+		q.set(key, values[0])
+		return nil
+	}
+	return q.setJSON(key, values)
+}
+
+func (q *query) encode() string {
+	return q.values.Encode()
+}
+
+// ## rpcParams are the JSON-RPC params of q, an empty object when q is nil
+func (q *query) rpcParams() map[string]any {
+	if q == nil {
+		return map[string]any{}
+	}
+	return q.params
+}
+
+// ## formatParam writes a parameter value, floats with every digit like Decimal
+func formatParam(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatDecimal(v)
+	case Decimal:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ## uri returns the request uri of urlPath with the query string of q, q may be nil
+func (c *Client) uri(urlPath string, q *query) string {
+	uri := c.baseURL + defaultAPIURL + urlPath
+	if q == nil {
+		return uri
+	}
+	if encoded := q.encode(); encoded != "" {
+		uri += "?" + encoded
+	}
+	return uri
+}
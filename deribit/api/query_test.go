@@ -0,0 +1,162 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// ## parseURI decodes the query string of a uri built by Client.uri
+func parseURI(t *testing.T, uri string) url.Values {
+	t.Helper()
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("url.Parse(%q): %v", uri, err)
+	}
+	values, err := url.ParseQuery(parsed.RawQuery)
+	if err != nil {
+		t.Fatalf("url.ParseQuery(%q): %v", parsed.RawQuery, err)
+	}
+	return values
+}
+
+func TestOrderQueryURI(t *testing.T) {
+	c := New("https://test.deribit.com", "id", "secret")
+
+	labels := []string{
+		"my order",
+		"a&b",
+		"price=100",
+		"a b&c=d?e#f%g+h",
+	}
+
+	for _, label := range labels {
+		request := &OrderRequest{
+			InstrumentName:  "BTC-PERPETUAL",
+			Amount:          10,
+			Type:            OrderTypeLimit,
+			Label:           label,
+			Price:           NewDecimal(0),
+			PostOnly:        true,
+			LinkedOrderType: LinkedOrderTypeOTOCO,
+			OTOCOConfig: []OTOCOConfig{
+				{Direction: "sell", Type: OrderTypeTakeLimit, Label: label + " take", Amount: 10, Price: NewDecimal(70000.5), TriggerPrice: 70000, Trigger: "mark_price"},
+				{Direction: "sell", Type: OrderTypeStopMarket, Label: "stop&loss=1", Amount: 10, TriggerPrice: 0.00000001, Trigger: "index_price"},
+				{Direction: "buy", Label: "spread at zero", Amount: 10, Price: NewDecimal(0)},
+			},
+		}
+
+		q, err := orderQuery(request)
+		if err != nil {
+			t.Fatalf("orderQuery: %v", err)
+		}
+		uri := c.uri(urlPathBuy, q)
+		if !strings.HasPrefix(uri, "https://test.deribit.com/api/v2/private/buy?") {
+			t.Fatalf("uri = %q, want the buy endpoint", uri)
+		}
+
+		values := parseURI(t, uri)
+		want := map[string]string{
+			"instrument_name":   "BTC-PERPETUAL",
+			"amount":            "10",
+			"type":              "limit",
+			"label":             label,
+			"price":             "0",
+			"post_only":         "true",
+			"linked_order_type": "one_triggers_one_cancels_other",
+		}
+		for key, value := range want {
+			if got := values.Get(key); got != value {
+				t.Errorf("label %q: %s = %q, want %q", label, key, got, value)
+			}
+		}
+		if len(values["label"]) != 1 {
+			t.Errorf("label %q: got %d label values, want 1", label, len(values["label"]))
+		}
+
+		var configs []OTOCOConfig
+		if err := json.Unmarshal([]byte(values.Get("otoco_config")), &configs); err != nil {
+			t.Fatalf("label %q: otoco_config %q: %v", label, values.Get("otoco_config"), err)
+		}
+		if len(configs) != len(request.OTOCOConfig) {
+			t.Fatalf("label %q: got %d otoco_config entries, want %d", label, len(configs), len(request.OTOCOConfig))
+		}
+		if !strings.Contains(values.Get("otoco_config"), `"price":0`) {
+			t.Errorf("label %q: otoco_config %q lost the zero price", label, values.Get("otoco_config"))
+		}
+		for i := range configs {
+			if !reflect.DeepEqual(configs[i], request.OTOCOConfig[i]) {
+				t.Errorf("label %q: otoco_config[%d] = %+v, want %+v", label, i, configs[i], request.OTOCOConfig[i])
+			}
+		}
+	}
+}
+
+func TestEditQueryURI(t *testing.T) {
+	c := New("https://test.deribit.com", "id", "secret")
+
+	postOnly := false
+	request := &EditRequest{
+		Label:          "grid 1&side=buy",
+		InstrumentName: "BTC-PERPETUAL",
+		Amount:         20,
+		Price:          NewDecimal(-12.5),
+		PostOnly:       &postOnly,
+	}
+
+	values := parseURI(t, c.uri(urlPathEditByLabel, editQuery(request)))
+	want := url.Values{
+		"label":           {"grid 1&side=buy"},
+		"instrument_name": {"BTC-PERPETUAL"},
+		"amount":          {"20"},
+		"price":           {"-12.5"},
+		"post_only":       {"false"},
+	}
+	if values.Encode() != want.Encode() {
+		t.Errorf("query = %q, want %q", values.Encode(), want.Encode())
+	}
+}
+
+func TestSimulateMarginsQueryURI(t *testing.T) {
+	c := New("https://test.deribit.com", "id", "secret")
+
+	simulated := map[string]float64{
+		"BTC-PERPETUAL":        1e-7,
+		"BTC-27DEC24-100000-C": -2.5,
+		"BTC-27DEC24":          1e21,
+	}
+
+	q, err := simulateMarginsQuery("BTC", true, simulated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := parseURI(t, c.uri(urlPathGetSimulateMargins, q))
+
+	if got := values.Get("currency"); got != "BTC" {
+		t.Errorf("currency = %q, want BTC", got)
+	}
+	if got := values.Get("add_positions"); got != "true" {
+		t.Errorf("add_positions = %q, want true", got)
+	}
+
+	raw := values.Get("simulated_positions")
+	if regexp.MustCompile(`[0-9][eE][-+]?[0-9]`).MatchString(raw) {
+		t.Errorf("simulated_positions %q is in exponent form", raw)
+	}
+	var positions map[string]float64
+	if err := json.Unmarshal([]byte(raw), &positions); err != nil {
+		t.Fatalf("simulated_positions %q: %v", raw, err)
+	}
+	if len(positions) != len(simulated) {
+		t.Fatalf("got %d simulated_positions, want %d", len(positions), len(simulated))
+	}
+	for instrumentName, size := range simulated {
+		if positions[instrumentName] != size {
+			t.Errorf("simulated_positions[%s] = %v, want %v", instrumentName, positions[instrumentName], size)
+		}
+	}
+}
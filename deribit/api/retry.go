@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// ErrOrderPlaced is returned instead of retrying when the dedup check finds the order of a failed attempt
+var ErrOrderPlaced = errors.New("order was placed by a failed attempt")
+
+// RetryPolicy is the exponential backoff of transient failures: network errors, 5xx, 429, 10028 and 10040.
+// The delay doubles from InitialDelay up to MaxDelay with random jitter.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt, 1 disables retries
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  3,
+		InitialDelay: 200 * time.Millisecond,
+		MaxDelay:     5 * time.Second,
+	}
+}
+
+// ## delay before the next attempt, attempt starts at 1
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// DedupFunc tells whether a non idempotent request that failed in an unknown state may be sent again,
+// it returns nil when nothing was executed since the first attempt started and an error otherwise.
+type DedupFunc func(ctx context.Context, since time.Time) error
+
+type callOptions struct {
+	retry      RetryPolicy
+	dedup      DedupFunc
+	labelDedup bool
+}
+
+// CallOption overrides the client settings for one call
+type CallOption func(o *callOptions)
+
+// WithRetry overrides the retry policy of the client for one call
+func WithRetry(policy RetryPolicy) CallOption {
+	return func(o *callOptions) {
+		o.retry = policy
+	}
+}
+
+// WithoutRetry sends the request once
+func WithoutRetry() CallOption {
+	return func(o *callOptions) {
+		o.retry.MaxAttempts = 1
+	}
+}
+
+// WithDedup allows retrying a non idempotent request (buy, sell, close_position or any private method that
+// creates something) after an ambiguous failure when dedup confirms the failed attempt did not execute.
+func WithDedup(dedup DedupFunc) CallOption {
+	return func(o *callOptions) {
+		o.dedup = dedup
+	}
+}
+
+// WithLabelDedup checks the label of a buy or sell after an ambiguous failure: the orders of the label are
+// listed before sending and a retry is only sent when no new order has the label, ErrOrderPlaced otherwise.
+// It costs one get_order_state_by_label request per order, a label should not be used by two orders in flight.
+func WithLabelDedup() CallOption {
+	return func(o *callOptions) {
+		o.labelDedup = true
+	}
+}
+
+func (c *Client) callOptions(opts []CallOption) callOptions {
+	o := callOptions{
+		retry: c.retryPolicy,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ## Private methods that only read or set an absolute state, sending them twice has the effect of once.
+// Every other private method (buy, sell, close_position, withdraw, create_subaccount, create_combo, block
+// trades, mass quotes...) may create something twice, it is retried after an ambiguous failure only with a
+// dedup check.
+var idempotentPrivatePrefixes = []string{
+	"private/get_",
+	"private/cancel",
+	"private/edit",
+	"private/set_",
+	"private/simulate_",
+	"private/enable_",
+	"private/disable_",
+	"private/reset_",
+	"private/toggle_",
+	"private/change_",
+	"private/update_",
+	"private/remove_",
+	"private/subscribe",
+	"private/unsubscribe",
+}
+
+var idempotentPrivateMethods = map[string]struct{}{
+	"private/logout":             {},
+	"private/pme/simulate":       {},
+	"private/verify_block_trade": {},
+}
+
+// ## isIdempotent is true for public methods and the allow-listed private ones
+func isIdempotent(rpcMethod string) bool {
+	if !strings.HasPrefix(rpcMethod, "private/") {
+		return true
+	}
+	for _, prefix := range idempotentPrivatePrefixes {
+		if strings.HasPrefix(rpcMethod, prefix) {
+			return true
+		}
+	}
+	_, ok := idempotentPrivateMethods[rpcMethod]
+	return ok
+}
+
+// ## retryable classifies err, safe is true when Deribit rejected the request without executing it
+func retryable(err error) (retry bool, safe bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, false
+	}
+
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.Code {
+		case CodeTooManyRequests, CodeRetry:
+			return true, true
+		}
+		return false, false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == 429:
+			return true, true
+		case statusErr.StatusCode >= 500:
+			return true, false
+		}
+		return false, false
+	}
+
+	// ## Network error, the request may or may not have reached Deribit
+	return true, false
+}
+
+// ## labelOrders lists the order ids of label on instrumentName
+func (s *OrderService) labelOrders(ctx context.Context, instrumentName string, label string) (map[string]struct{}, error) {
+	resp, err := s.GetOrderStateByLabel(ctx, currencyOf(instrumentName), label)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIDs := make(map[string]struct{}, len(resp.Result))
+	for _, order := range resp.Result {
+		if order.InstrumentName == instrumentName {
+			orderIDs[order.OrderID] = struct{}{}
+		}
+	}
+	return orderIDs, nil
+}
+
+// ## labelDedup looks for an order with label that was not there before the first attempt,
+// a reused label of an earlier order is not taken for the order of the failed attempt
+func (s *OrderService) labelDedup(instrumentName string, label string, known map[string]struct{}) DedupFunc {
+	return func(ctx context.Context, since time.Time) error {
+		orderIDs, err := s.labelOrders(ctx, instrumentName, label)
+		if err != nil {
+			return fmt.Errorf("dedup check of label %s failed: %w", label, err)
+		}
+
+		for orderID := range orderIDs {
+			if _, found := known[orderID]; !found {
+				return fmt.Errorf("%w: order_id %s, label %s", ErrOrderPlaced, orderID, label)
+			}
+		}
+		return nil
+	}
+}
+
+// ## currencyOf returns the currency of an instrument, BTC-PERPETUAL is BTC and BTC_USDC-PERPETUAL is USDC
+func currencyOf(instrumentName string) string {
+	base, _, _ := strings.Cut(instrumentName, "-")
+	if _, quote, found := strings.Cut(base, "_"); found {
+		return quote
+	}
+	return base
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIsIdempotent(t *testing.T) {
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{"public/get_instruments", true},
+		{"public/auth", true},
+		{"private/get_positions", true},
+		{"private/get_order_state_by_label", true},
+		{"private/cancel", true},
+		{"private/cancel_all_by_currency", true},
+		{"private/edit", true},
+		{"private/edit_by_label", true},
+		{"private/set_mmp_config", true},
+		{"private/change_subaccount_name", true},
+		{"private/logout", true},
+
+		{"private/buy", false},
+		{"private/sell", false},
+		{"private/close_position", false},
+		{"private/withdraw", false},
+		{"private/submit_transfer_to_subaccount", false},
+		{"private/submit_transfer_to_user", false},
+		{"private/create_subaccount", false},
+		{"private/create_deposit_address", false},
+		{"private/create_combo", false},
+		{"private/execute_block_trade", false},
+		{"private/mass_quote", false},
+		{"private/some_future_method", false},
+	}
+
+	for _, tt := range tests {
+		if got := isIdempotent(tt.method); got != tt.want {
+			t.Errorf("isIdempotent(%q) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantRetry bool
+		wantSafe  bool
+	}{
+		{"too many requests", &Error{Code: CodeTooManyRequests, Message: "too_many_requests"}, true, true},
+		{"retry", &Error{Code: CodeRetry, Message: "retry"}, true, true},
+		{"wrapped rpc error", fmt.Errorf("private/buy: %w", &Error{Code: CodeRetry}), true, true},
+		{"not enough funds", &Error{Code: CodeNotEnoughFunds, Message: "not_enough_funds"}, false, false},
+		{"wrong tick", &Error{Code: CodePriceWrongTick}, false, false},
+		{"unauthorized", &Error{Code: CodeUnauthorized}, false, false},
+		{"http 429", &StatusError{StatusCode: 429}, true, true},
+		{"http 500", &StatusError{StatusCode: 500}, true, false},
+		{"http 502", &StatusError{StatusCode: 502}, true, false},
+		{"http 503", fmt.Errorf("wrapped: %w", &StatusError{StatusCode: 503}), true, false},
+		{"http 400", &StatusError{StatusCode: 400}, false, false},
+		{"http 404", &StatusError{StatusCode: 404}, false, false},
+		{"canceled", context.Canceled, false, false},
+		{"deadline", fmt.Errorf("request: %w", context.DeadlineExceeded), false, false},
+		{"network", errors.New("read tcp: connection reset by peer"), true, false},
+	}
+
+	for _, tt := range tests {
+		retry, safe := retryable(tt.err)
+		if retry != tt.wantRetry || safe != tt.wantSafe {
+			t.Errorf("%s: retryable() = %v, %v, want %v, %v", tt.name, retry, safe, tt.wantRetry, tt.wantSafe)
+		}
+	}
+}
+
+// ## retryServer answers public/auth and fails every other method with status, hits counts them by method
+func retryServer(t *testing.T, status int, handle func(method string, w http.ResponseWriter) bool) (*httptest.Server, map[string]int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	hits := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		method := req.Method
+		if method == "" {
+			method = strings.TrimPrefix(r.URL.Path, "/api/v2/")
+		}
+
+		mu.Lock()
+		hits[method]++
+		mu.Unlock()
+
+		if method == "public/auth" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"access_token":"token","refresh_token":"refresh","expires_in":900}}`, req.ID)
+			return
+		}
+		if handle != nil && handle(method, w) {
+			return
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, "bad gateway")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func TestRequestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	order := func() *OrderRequest {
+		return &OrderRequest{InstrumentName: "BTC-PERPETUAL", Amount: 10, Price: NewDecimal(65000), Label: "grid-1"}
+	}
+	dedupCalls := 0
+	dedupNone := WithDedup(func(ctx context.Context, since time.Time) error {
+		dedupCalls++
+		return nil
+	})
+	dedupPlaced := WithDedup(func(ctx context.Context, since time.Time) error {
+		dedupCalls++
+		return fmt.Errorf("%w: order_id ETH-1", ErrOrderPlaced)
+	})
+
+	tests := []struct {
+		name      string
+		status    int
+		method    string
+		send      func(ctx context.Context, c *Client) error
+		wantHits  int
+		wantDedup int
+		wantErr   error
+	}{
+		{"idempotent read is retried after 502", 502, "private/get_positions", func(ctx context.Context, c *Client) error {
+			return c.Call(ctx, "private/get_positions", map[string]any{"currency": "BTC"}, nil)
+		}, 3, 0, nil},
+		{"buy is not resent after 502 without dedup", 502, "private/buy", func(ctx context.Context, c *Client) error {
+			_, err := c.Orders.PostBuy(ctx, order())
+			return err
+		}, 1, 0, nil},
+		{"create_subaccount is not resent after 502", 502, "private/create_subaccount", func(ctx context.Context, c *Client) error {
+			return c.Call(ctx, "private/create_subaccount", nil, nil)
+		}, 1, 0, nil},
+		{"buy is resent when dedup finds nothing", 502, "private/buy", func(ctx context.Context, c *Client) error {
+			_, err := c.Orders.PostBuy(ctx, order(), dedupNone)
+			return err
+		}, 3, 2, nil},
+		{"buy stops when dedup finds the order", 502, "private/buy", func(ctx context.Context, c *Client) error {
+			_, err := c.Orders.PostBuy(ctx, order(), dedupPlaced)
+			return err
+		}, 1, 1, ErrOrderPlaced},
+		{"buy is resent after 429 without dedup", 429, "private/buy", func(ctx context.Context, c *Client) error {
+			_, err := c.Orders.PostBuy(ctx, order())
+			return err
+		}, 3, 0, nil},
+		{"buy is not resent after 400", 400, "private/buy", func(ctx context.Context, c *Client) error {
+			_, err := c.Orders.PostBuy(ctx, order(), dedupNone)
+			return err
+		}, 1, 0, nil},
+		{"WithoutRetry sends once", 502, "private/get_positions", func(ctx context.Context, c *Client) error {
+			return c.CallWithOptions(ctx, "private/get_positions", nil, nil, WithoutRetry())
+		}, 1, 0, nil},
+	}
+
+	for _, tt := range tests {
+		srv, hits := retryServer(t, tt.status, nil)
+		c := New(srv.URL, "id", "secret", WithRetryPolicy(policy))
+		dedupCalls = 0
+
+		err := tt.send(context.Background(), c)
+		if err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if hits[tt.method] != tt.wantHits {
+			t.Errorf("%s: %s sent %d times, want %d", tt.name, tt.method, hits[tt.method], tt.wantHits)
+		}
+		if dedupCalls != tt.wantDedup {
+			t.Errorf("%s: dedup called %d times, want %d", tt.name, dedupCalls, tt.wantDedup)
+		}
+	}
+}
+
+func TestLabelDedup(t *testing.T) {
+	var orders string
+	srv, _ := retryServer(t, 502, func(method string, w http.ResponseWriter) bool {
+		if method != "private/get_order_state_by_label" {
+			return false
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s}`, orders)
+		return true
+	})
+	c := New(srv.URL, "id", "secret")
+	ctx := context.Background()
+
+	orders = `[{"order_id":"BTC-1","instrument_name":"BTC-PERPETUAL","label":"grid"},
+		{"order_id":"BTC-2","instrument_name":"BTC-27DEC24","label":"grid"}]`
+	known, err := c.Orders.labelOrders(ctx, "BTC-PERPETUAL", "grid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := known["BTC-1"]; !ok || len(known) != 1 {
+		t.Fatalf("labelOrders = %v, want only BTC-1 of the instrument", known)
+	}
+	dedup := c.Orders.labelDedup("BTC-PERPETUAL", "grid", known)
+
+	tests := []struct {
+		name    string
+		orders  string
+		wantErr error
+	}{
+		{"no order", `[]`, nil},
+		{"only the earlier order of the label", `[{"order_id":"BTC-1","instrument_name":"BTC-PERPETUAL","label":"grid"}]`, nil},
+		{"a new order on another instrument", `[{"order_id":"BTC-3","instrument_name":"BTC-27DEC24","label":"grid"}]`, nil},
+		{"a new order of the label", `[{"order_id":"BTC-1","instrument_name":"BTC-PERPETUAL","label":"grid"},
+			{"order_id":"BTC-4","instrument_name":"BTC-PERPETUAL","label":"grid"}]`, ErrOrderPlaced},
+	}
+
+	for _, tt := range tests {
+		orders = tt.orders
+		err := dedup(ctx, time.Now())
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: dedup() = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// ## A failed lookup is not taken for "nothing placed"
+	orders = `not json`
+	if err := dedup(ctx, time.Now()); err == nil || errors.Is(err, ErrOrderPlaced) {
+		t.Errorf("dedup() with a failed lookup = %v, want a lookup error", err)
+	}
+}
+
+func TestCurrencyOf(t *testing.T) {
+	tests := map[string]string{
+		"BTC-PERPETUAL":       "BTC",
+		"ETH-27DEC24-4000-C":  "ETH",
+		"BTC_USDC-PERPETUAL":  "USDC",
+		"ETH_USDC":            "USDC",
+		"BTC-FS-27DEC24_PERP": "BTC",
+	}
+
+	for instrumentName, want := range tests {
+		if got := currencyOf(instrumentName); got != want {
+			t.Errorf("currencyOf(%q) = %q, want %q", instrumentName, got, want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"errors"
+)
+
+// ## Scoped sessions: exchange_token switches to a subaccount, fork_token opens a named session
+
+const (
+	urlPathExchangeToken = "/public/exchange_token"
+	urlPathForkToken     = "/public/fork_token"
+)
+
+type subaccountKey struct{}
+
+// ActAsSubaccount returns a ctx whose private requests run as subaccount subjectID. The client exchanges
+// its own refresh token for a session of the subaccount on first use and keeps it renewed.
+func ActAsSubaccount(ctx context.Context, subjectID int64) context.Context {
+	return context.WithValue(ctx, subaccountKey{}, subjectID)
+}
+
+// ## subaccountFrom returns the subaccount set by ActAsSubaccount, 0 for the main session
+func subaccountFrom(ctx context.Context) int64 {
+	subjectID, _ := ctx.Value(subaccountKey{}).(int64)
+	return subjectID
+}
+
+// ## signs tells whether a private request of ctx carries a signature instead of a bearer token,
+// subaccount sessions always use the token of exchange_token
+func (c *Client) signs(ctx context.Context) bool {
+	return c.signatureAuth && subaccountFrom(ctx) == 0
+}
+
+// ExchangeToken returns a session of subaccount subjectID from the refresh token of the client,
+// the session of the client is kept
+func ExchangeToken(ctx context.Context, c *Client, subjectID int64) (*AuthResponse, error) {
+	if _, err := c.token(ctx); err != nil {
+		return nil, err
+	}
+
+	return exchangeToken(ctx, c, subjectID)
+}
+
+func exchangeToken(ctx context.Context, c *Client, subjectID int64) (*AuthResponse, error) {
+	c.tokenMu.RLock()
+	refreshToken := c.session.refreshToken
+	c.tokenMu.RUnlock()
+
+	if refreshToken == "" {
+		return nil, errors.New("no refresh token, authenticate first")
+	}
+
+	q := newQuery().
+		set("refresh_token", refreshToken).
+		set("subject_id", subjectID)
+
+	return requestToken(ctx, c, "exchange_token", urlPathExchangeToken, q)
+}
+
+// ForkToken returns a new session named sessionName from the refresh token of the client,
+// the session of the client is kept
+func ForkToken(ctx context.Context, c *Client, sessionName string) (*AuthResponse, error) {
+	if _, err := c.token(ctx); err != nil {
+		return nil, err
+	}
+
+	c.tokenMu.RLock()
+	refreshToken := c.session.refreshToken
+	c.tokenMu.RUnlock()
+
+	if refreshToken == "" {
+		return nil, errors.New("no refresh token, authenticate first")
+	}
+
+	q := newQuery().
+		set("refresh_token", refreshToken).
+		set("session_name", sessionName)
+
+	return requestToken(ctx, c, "fork_token", urlPathForkToken, q)
+}
+
+// ## sessionToken returns the access token for ctx, of the subaccount set by ActAsSubaccount or of the client
+func (c *Client) sessionToken(ctx context.Context) (string, error) {
+	subjectID := subaccountFrom(ctx)
+	if subjectID == 0 {
+		return c.token(ctx)
+	}
+
+	c.tokenMu.RLock()
+	current, found := c.subaccounts[subjectID]
+	if found && current.valid() {
+		token := current.accessToken
+		c.tokenMu.RUnlock()
+		return token, nil
+	}
+	c.tokenMu.RUnlock()
+
+	return c.renewSessionToken(ctx, "")
+}
+
+// ## renewSessionToken replaces a rejected or expiring token of the session of ctx
+func (c *Client) renewSessionToken(ctx context.Context, stale string) (string, error) {
+	subjectID := subaccountFrom(ctx)
+	if subjectID == 0 {
+		return c.renewToken(ctx, stale)
+	}
+
+	// ## The exchange needs a valid refresh token of the client, renew it before taking authMu
+	if _, err := c.token(ctx); err != nil {
+		return "", err
+	}
+
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	c.tokenMu.RLock()
+	var current session
+	if s, found := c.subaccounts[subjectID]; found {
+		current = *s
+	}
+	c.tokenMu.RUnlock()
+
+	if current.accessToken != stale && current.valid() {
+		return current.accessToken, nil
+	}
+
+	var resp *AuthResponse
+	if current.refreshToken != "" {
+		var err error
+		resp, err = requestToken(ctx, c, "refresh_token", urlPathAuth, refreshQuery(current.refreshToken))
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", err
+			}
+			c.logger.Warn("failed to refresh subaccount token, exchange again", "subject_id", subjectID, "error", err)
+		}
+	}
+
+	if resp == nil {
+		var err error
+		if resp, err = exchangeToken(ctx, c, subjectID); err != nil {
+			return "", err
+		}
+	}
+
+	c.tokenMu.Lock()
+	current.set(resp.Result)
+	c.subaccounts[subjectID] = &current
+	c.tokenMu.Unlock()
+
+	c.logger.Debug("subaccount session renewed", "subject_id", subjectID, "result", resp.Result)
+
+	return resp.Result.AccessToken, nil
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
+	"github.com/valyala/fasthttp"
+)
+
+// ## client_signature authentication, the client secret signs the request and is never sent
+
+const signatureScheme = "deri-hmac-sha256"
+
+// Signature is the hex HMAC-SHA256 of timestamp, nonce and data keyed by the client secret,
+// it is the signature of grant_type=client_signature and of the deri-hmac-sha256 header
+func Signature(clientSecret string, timestamp int64, nonce string, data string) string {
+	return deribit.Signature(clientSecret, timestamp, nonce, data)
+}
+
+// NewNonce returns a random nonce, a nonce is never used twice with the same timestamp
+func NewNonce() string {
+	return deribit.NewNonce()
+}
+
+// Clock is deribit.Clock, the local time corrected by the offset to the Deribit server time
+type Clock = deribit.Clock
+
+// SyncClock sets the clock offset used by signatures from public/get_time and returns it
+func (c *Client) SyncClock(ctx context.Context) (time.Duration, error) {
+	sent := time.Now()
+	resp, err := c.Markets.GetTime(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sync clock: %w", err)
+	}
+
+	offset := c.clock.Sync(resp.Result, sent, time.Now())
+	c.logger.Debug("clock synced", "offset", offset)
+
+	return offset, nil
+}
+
+// AuthenticateWithSignature opens a session with grant_type=client_signature, the client secret is not sent
+func AuthenticateWithSignature(ctx context.Context, c *Client) (*AuthResponse, error) {
+	if c.clientID == "" || len(c.clientSecret) == 0 {
+		return nil, errors.New("API clientID and clientSecret not configured")
+	}
+
+	timestamp := c.clock.Now().UnixMilli()
+	nonce := NewNonce()
+
+	authRequest := &AuthRequest{
+		GrantType: "client_signature",
+		ClientID:  c.clientID,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: Signature(c.clientSecret, timestamp, nonce, ""),
+	}
+
+	q := newQuery().
+		set("grant_type", authRequest.GrantType).
+		set("client_id", authRequest.ClientID).
+		set("timestamp", authRequest.Timestamp).
+		set("nonce", authRequest.Nonce).
+		set("signature", authRequest.Signature).
+		set("data", "")
+
+	return sendAuth(ctx, c, authRequest, q)
+}
+
+// ## signatureHeader signs req for the deri-hmac-sha256 scheme, it is set again on every attempt
+// so a retry never reuses a nonce
+func (c *Client) signatureHeader(req *fasthttp.Request) string {
+	timestamp := c.clock.Now().UnixMilli()
+	nonce := NewNonce()
+
+	data := fmt.Sprintf(
+		"%s\n%s\n%s\n",
+		strings.ToUpper(string(req.Header.Method())),
+		req.URI().RequestURI(),
+		req.Body(),
+	)
+
+	return fmt.Sprintf(
+		"%s id=%s,ts=%d,sig=%s,nonce=%s",
+		signatureScheme,
+		c.clientID,
+		timestamp,
+		Signature(c.clientSecret, timestamp, nonce, data),
+		nonce,
+	)
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
+)
+
+type SubaccountService struct {
+	client *Client
+}
+
+const (
+	urlPathCreateSubaccount                  = "/private/create_subaccount"
+	urlPathGetSubaccounts                    = "/private/get_subaccounts"
+	urlPathGetSubaccountsDetails             = "/private/get_subaccounts_details"
+	urlPathChangeSubaccountName              = "/private/change_subaccount_name"
+	urlPathSetEmailForSubaccount             = "/private/set_email_for_subaccount"
+	urlPathToggleSubaccountLogin             = "/private/toggle_subaccount_login"
+	urlPathToggleNotificationsFromSubaccount = "/private/toggle_notifications_from_subaccount"
+	urlPathRemoveSubaccount                  = "/private/remove_subaccount"
+)
+
+// ## Subaccount shapes are the deribit types shared with the ws client
+type (
+	PortfolioItem = deribit.PortfolioItem
+	Portfolio     = deribit.Portfolio
+	SubAccount    = deribit.SubAccount
+)
+
+type CreateSubAccountResponse struct {
+	ID      uint64     `json:"id"`
+	JSONRPC string     `json:"jsonrpc"`
+	Result  SubAccount `json:"result"`
+}
+
+type SubAccountsResponse struct {
+	ID      uint64       `json:"id"`
+	JSONRPC string       `json:"jsonrpc"`
+	Result  []SubAccount `json:"result"`
+}
+
+type SubAccountsDetail struct {
+	OpenOrders []OrderState `json:"open_orders"`
+	Positions  []Position   `json:"positions"`
+	UID        int          `json:"uid"`
+}
+
+type SubAccountsDetailsResponse struct {
+	ID      uint64              `json:"id"`
+	JSONRPC string              `json:"jsonrpc"`
+	Result  []SubAccountsDetail `json:"result"`
+}
+
+// SubAccountUpdateResponse is the answer of the subaccount settings methods, Result is "ok"
+type SubAccountUpdateResponse struct {
+	ID      uint64 `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Result  string `json:"result"`
+}
+
+// ## Create a new subaccount
+func (s *SubaccountService) CreateSubaccount(ctx context.Context) (*CreateSubAccountResponse, error) {
+	var resp CreateSubAccountResponse
+
+	err := s.client.request(ctx, urlPathCreateSubaccount, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Get all subaccounts, with their balances when withPortfolio is true
+func (s *SubaccountService) GetSubaccounts(ctx context.Context, withPortfolio bool) (*SubAccountsResponse, error) {
+	var resp SubAccountsResponse
+	q := newQuery().set("with_portfolio", withPortfolio)
+
+	err := s.client.request(ctx, urlPathGetSubaccounts, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Get the positions, and open orders when withOpenOrders is true, of every subaccount in currency
+func (s *SubaccountService) GetSubaccountsDetails(
+	ctx context.Context,
+	currency string,
+	withOpenOrders bool,
+) (*SubAccountsDetailsResponse, error) {
+	var resp SubAccountsDetailsResponse
+	q := newQuery().
+		set("currency", currency).
+		set("with_open_orders", withOpenOrders)
+
+	err := s.client.request(ctx, urlPathGetSubaccountsDetails, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Change the user name of subaccount sid
+func (s *SubaccountService) ChangeSubaccountName(ctx context.Context, sid int, name string) (*SubAccountUpdateResponse, error) {
+	q := newQuery().
+		set("sid", sid).
+		set("name", name)
+
+	return s.update(ctx, urlPathChangeSubaccountName, q)
+}
+
+// ## Assign an email to subaccount sid, Deribit sends a confirmation to it
+func (s *SubaccountService) SetEmailForSubaccount(ctx context.Context, sid int, email string) (*SubAccountUpdateResponse, error) {
+	q := newQuery().
+		set("sid", sid).
+		set("email", email)
+
+	return s.update(ctx, urlPathSetEmailForSubaccount, q)
+}
+
+// ## Enable or disable the login of subaccount sid, disabling it also terminates its sessions
+func (s *SubaccountService) ToggleSubaccountLogin(ctx context.Context, sid int, enabled bool) (*SubAccountUpdateResponse, error) {
+	state := "disable"
+	if enabled {
+		state = "enable"
+	}
+
+	q := newQuery().
+		set("sid", sid).
+		set("state", state)
+
+	return s.update(ctx, urlPathToggleSubaccountLogin, q)
+}
+
+// ## Enable or disable the notifications of subaccount sid to the main account
+func (s *SubaccountService) ToggleNotificationsFromSubaccount(ctx context.Context, sid int, enabled bool) (*SubAccountUpdateResponse, error) {
+	q := newQuery().
+		set("sid", sid).
+		set("state", enabled)
+
+	return s.update(ctx, urlPathToggleNotificationsFromSubaccount, q)
+}
+
+// ## Remove subaccount subaccountID, it must have no balance, positions or open orders
+func (s *SubaccountService) RemoveSubaccount(ctx context.Context, subaccountID int) (*SubAccountUpdateResponse, error) {
+	q := newQuery().set("subaccount_id", subaccountID)
+
+	return s.update(ctx, urlPathRemoveSubaccount, q)
+}
+
+func (s *SubaccountService) update(ctx context.Context, urlPath string, q *query) (*SubAccountUpdateResponse, error) {
+	var resp SubAccountUpdateResponse
+	err := s.client.request(ctx, urlPath, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
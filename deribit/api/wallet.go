@@ -0,0 +1,434 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+type WalletService struct {
+	client *Client
+}
+
+const (
+	urlPathGetCurrentDepositAddress   = "/private/get_current_deposit_address"
+	urlPathCreateDepositAddress       = "/private/create_deposit_address"
+	urlPathGetDeposits                = "/private/get_deposits"
+	urlPathGetWithdrawals             = "/private/get_withdrawals"
+	urlPathWithdraw                   = "/private/withdraw"
+	urlPathCancelWithdrawal           = "/private/cancel_withdrawal"
+	urlPathGetTransfers               = "/private/get_transfers"
+	urlPathSubmitTransferToSubaccount = "/private/submit_transfer_to_subaccount"
+	urlPathSubmitTransferToUser       = "/private/submit_transfer_to_user"
+	urlPathCancelTransferByID         = "/private/cancel_transfer_by_id"
+	urlPathGetAddressBook             = "/private/get_address_book"
+	urlPathAddToAddressBook           = "/private/add_to_address_book"
+	urlPathUpdateInAddressBook        = "/private/update_in_address_book"
+	urlPathRemoveFromAddressBook      = "/private/remove_from_address_book"
+)
+
+// ## Address book types
+const (
+	AddressTypeTransfer      = "transfer"
+	AddressTypeWithdrawal    = "withdrawal"
+	AddressTypeDepositSource = "deposit_source"
+)
+
+// ErrFundTransfersDisabled is returned by withdrawals and transfers unless the client was created with WithFundTransfers
+var ErrFundTransfersDisabled = errors.New("fund transfers are disabled, create the client with WithFundTransfers")
+
+// ## fundTransferMethods move funds out of the account, the services and Call send them only with WithFundTransfers
+var fundTransferMethods = map[string]bool{
+	strings.TrimPrefix(urlPathWithdraw, "/"):                   true,
+	strings.TrimPrefix(urlPathSubmitTransferToSubaccount, "/"): true,
+	strings.TrimPrefix(urlPathSubmitTransferToUser, "/"):       true,
+}
+
+// ## ------------------------------------------------------------------------
+
+type DepositAddress struct {
+	Address           string `json:"address"`
+	CreationTimestamp int64  `json:"creation_timestamp"`
+	Currency          string `json:"currency"`
+	Type              string `json:"type"`
+}
+
+// DepositAddressResponse Result is nil when the currency has no deposit address yet
+type DepositAddressResponse struct {
+	ID      uint64          `json:"id"`
+	JSONRPC string          `json:"jsonrpc"`
+	Result  *DepositAddress `json:"result"`
+}
+
+type Deposit struct {
+	Address           string  `json:"address"`
+	Amount            float64 `json:"amount"`
+	Currency          string  `json:"currency"`
+	Note              string  `json:"note"`
+	ReceivedTimestamp int64   `json:"received_timestamp"`
+	State             string  `json:"state"`
+	TransactionID     string  `json:"transaction_id"`
+	UpdatedTimestamp  int64   `json:"updated_timestamp"`
+}
+
+type DepositsResponse struct {
+	ID      uint64 `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Result  struct {
+		Count int       `json:"count"`
+		Data  []Deposit `json:"data"`
+	} `json:"result"`
+}
+
+type Withdrawal struct {
+	Address            string  `json:"address"`
+	Amount             float64 `json:"amount"`
+	ConfirmedTimestamp int64   `json:"confirmed_timestamp"`
+	CreatedTimestamp   int64   `json:"created_timestamp"`
+	Currency           string  `json:"currency"`
+	Fee                float64 `json:"fee"`
+	ID                 int64   `json:"id"`
+	Priority           float64 `json:"priority"`
+	State              string  `json:"state"`
+	TransactionID      string  `json:"transaction_id"`
+	UpdatedTimestamp   int64   `json:"updated_timestamp"`
+}
+
+type WithdrawalResponse struct {
+	ID      uint64     `json:"id"`
+	JSONRPC string     `json:"jsonrpc"`
+	Result  Withdrawal `json:"result"`
+}
+
+type WithdrawalsResponse struct {
+	ID      uint64 `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Result  struct {
+		Count int          `json:"count"`
+		Data  []Withdrawal `json:"data"`
+	} `json:"result"`
+}
+
+type Transfer struct {
+	Amount           float64 `json:"amount"`
+	CreatedTimestamp int64   `json:"created_timestamp"`
+	Currency         string  `json:"currency"`
+	// Direction is payment or income
+	Direction        string `json:"direction"`
+	ID               int64  `json:"id"`
+	OtherSide        string `json:"other_side"`
+	State            string `json:"state"`
+	Type             string `json:"type"`
+	UpdatedTimestamp int64  `json:"updated_timestamp"`
+}
+
+type TransferResponse struct {
+	ID      uint64   `json:"id"`
+	JSONRPC string   `json:"jsonrpc"`
+	Result  Transfer `json:"result"`
+}
+
+type TransfersResponse struct {
+	ID      uint64 `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Result  struct {
+		Count int        `json:"count"`
+		Data  []Transfer `json:"data"`
+	} `json:"result"`
+}
+
+type AddressBookEntry struct {
+	Address                string `json:"address"`
+	Agreed                 bool   `json:"agreed"`
+	BeneficiaryAddress     string `json:"beneficiary_address"`
+	BeneficiaryCompanyName string `json:"beneficiary_company_name"`
+	BeneficiaryFirstName   string `json:"beneficiary_first_name"`
+	BeneficiaryLastName    string `json:"beneficiary_last_name"`
+	BeneficiaryVaspDid     string `json:"beneficiary_vasp_did"`
+	BeneficiaryVaspName    string `json:"beneficiary_vasp_name"`
+	BeneficiaryVaspWebsite string `json:"beneficiary_vasp_website"`
+	CreationTimestamp      int64  `json:"creation_timestamp"`
+	Currency               string `json:"currency"`
+	Label                  string `json:"label"`
+	Personal               bool   `json:"personal"`
+	Status                 string `json:"status"`
+	Type                   string `json:"type"`
+}
+
+type AddressBookEntryResponse struct {
+	ID      uint64           `json:"id"`
+	JSONRPC string           `json:"jsonrpc"`
+	Result  AddressBookEntry `json:"result"`
+}
+
+type AddressBookResponse struct {
+	ID      uint64             `json:"id"`
+	JSONRPC string             `json:"jsonrpc"`
+	Result  []AddressBookEntry `json:"result"`
+}
+
+// RemoveFromAddressBookResponse Result is "ok"
+type RemoveFromAddressBookResponse struct {
+	ID      uint64 `json:"id"`
+	JSONRPC string `json:"jsonrpc"`
+	Result  string `json:"result"`
+}
+
+// AddressBookRequest adds or updates an address book entry, withdrawal addresses need the beneficiary
+// details required by the travel rule
+type AddressBookRequest struct {
+	Currency               string
+	Type                   string
+	Address                string
+	Label                  string
+	BeneficiaryVaspName    string
+	BeneficiaryVaspDid     string
+	BeneficiaryVaspWebsite string
+	BeneficiaryFirstName   string
+	BeneficiaryLastName    string
+	BeneficiaryCompanyName string
+	BeneficiaryAddress     string
+	Agreed                 bool
+	Personal               bool
+}
+
+func (r *AddressBookRequest) query() *query {
+	return newQuery().
+		set("currency", r.Currency).
+		set("type", r.Type).
+		set("address", r.Address).
+		setOptional("label", r.Label).
+		setOptional("beneficiary_vasp_name", r.BeneficiaryVaspName).
+		setOptional("beneficiary_vasp_did", r.BeneficiaryVaspDid).
+		setOptional("beneficiary_vasp_website", r.BeneficiaryVaspWebsite).
+		setOptional("beneficiary_first_name", r.BeneficiaryFirstName).
+		setOptional("beneficiary_last_name", r.BeneficiaryLastName).
+		setOptional("beneficiary_company_name", r.BeneficiaryCompanyName).
+		setOptional("beneficiary_address", r.BeneficiaryAddress).
+		set("agreed", r.Agreed).
+		set("personal", r.Personal)
+}
+
+// ## ------------------------------------------------------------------------
+
+// ## checkFundTransfer guards every method that moves funds out of the account, whatever sends it
+func (c *Client) checkFundTransfer(method string) error {
+	if fundTransferMethods[method] && !c.fundTransfers {
+		return ErrFundTransfersDisabled
+	}
+	return nil
+}
+
+// ## Get the current deposit address of currency
+func (s *WalletService) GetCurrentDepositAddress(ctx context.Context, currency string) (*DepositAddressResponse, error) {
+	var resp DepositAddressResponse
+	q := newQuery().set("currency", currency)
+
+	err := s.client.request(ctx, urlPathGetCurrentDepositAddress, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Create a new deposit address of currency
+func (s *WalletService) CreateDepositAddress(ctx context.Context, currency string) (*DepositAddressResponse, error) {
+	var resp DepositAddressResponse
+	q := newQuery().set("currency", currency)
+
+	err := s.client.request(ctx, urlPathCreateDepositAddress, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Get the deposits of currency, newest first, count 0 uses the Deribit default
+func (s *WalletService) GetDeposits(ctx context.Context, currency string, count int, offset int) (*DepositsResponse, error) {
+	var resp DepositsResponse
+
+	err := s.client.request(ctx, urlPathGetDeposits, pageQuery(currency, count, offset), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Get the withdrawals of currency, newest first, count 0 uses the Deribit default
+func (s *WalletService) GetWithdrawals(ctx context.Context, currency string, count int, offset int) (*WithdrawalsResponse, error) {
+	var resp WithdrawalsResponse
+
+	err := s.client.request(ctx, urlPathGetWithdrawals, pageQuery(currency, count, offset), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Withdraw amount of currency to an address of the address book, priority is only used by BTC (e.g. "high")
+func (s *WalletService) Withdraw(
+	ctx context.Context,
+	currency string,
+	address string,
+	amount float64,
+	priority string,
+) (*WithdrawalResponse, error) {
+	q := newQuery().
+		set("currency", currency).
+		set("address", address).
+		set("amount", amount).
+		setOptional("priority", priority)
+
+	var resp WithdrawalResponse
+
+	err := s.client.request(ctx, urlPathWithdraw, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Cancel a withdrawal that is not processed yet
+func (s *WalletService) CancelWithdrawal(ctx context.Context, currency string, id int64) (*WithdrawalResponse, error) {
+	var resp WithdrawalResponse
+	q := newQuery().
+		set("currency", currency).
+		set("id", id)
+
+	err := s.client.request(ctx, urlPathCancelWithdrawal, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Get the transfers of currency, newest first, count 0 uses the Deribit default
+func (s *WalletService) GetTransfers(ctx context.Context, currency string, count int, offset int) (*TransfersResponse, error) {
+	var resp TransfersResponse
+
+	err := s.client.request(ctx, urlPathGetTransfers, pageQuery(currency, count, offset), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Transfer amount of currency to subaccount destination of the same main account
+func (s *WalletService) SubmitTransferToSubaccount(
+	ctx context.Context,
+	currency string,
+	amount float64,
+	destination int,
+) (*TransferResponse, error) {
+	var resp TransferResponse
+	q := newQuery().
+		set("currency", currency).
+		set("amount", amount).
+		set("destination", destination)
+
+	err := s.client.request(ctx, urlPathSubmitTransferToSubaccount, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Transfer amount of currency to destination, a transfer address of another user in the address book
+func (s *WalletService) SubmitTransferToUser(
+	ctx context.Context,
+	currency string,
+	amount float64,
+	destination string,
+) (*TransferResponse, error) {
+	var resp TransferResponse
+	q := newQuery().
+		set("currency", currency).
+		set("amount", amount).
+		set("destination", destination)
+
+	err := s.client.request(ctx, urlPathSubmitTransferToUser, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Cancel a transfer that is not processed yet
+func (s *WalletService) CancelTransferByID(ctx context.Context, currency string, id int64) (*TransferResponse, error) {
+	var resp TransferResponse
+	q := newQuery().
+		set("currency", currency).
+		set("id", id)
+
+	err := s.client.request(ctx, urlPathCancelTransferByID, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Get the address book entries of currency and type (AddressType*)
+func (s *WalletService) GetAddressBook(ctx context.Context, currency string, addressType string) (*AddressBookResponse, error) {
+	var resp AddressBookResponse
+	q := newQuery().
+		set("currency", currency).
+		set("type", addressType)
+
+	err := s.client.request(ctx, urlPathGetAddressBook, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Add an address to the address book, new withdrawal addresses must be confirmed by email before use
+func (s *WalletService) AddToAddressBook(ctx context.Context, request *AddressBookRequest) (*AddressBookEntryResponse, error) {
+	return s.addressBook(ctx, urlPathAddToAddressBook, request)
+}
+
+// ## Update the label and beneficiary details of an address book entry
+func (s *WalletService) UpdateInAddressBook(ctx context.Context, request *AddressBookRequest) (*AddressBookEntryResponse, error) {
+	return s.addressBook(ctx, urlPathUpdateInAddressBook, request)
+}
+
+func (s *WalletService) addressBook(ctx context.Context, urlPath string, request *AddressBookRequest) (*AddressBookEntryResponse, error) {
+	var resp AddressBookEntryResponse
+
+	err := s.client.request(ctx, urlPath, request.query(), &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## Remove an address from the address book
+func (s *WalletService) RemoveFromAddressBook(
+	ctx context.Context,
+	currency string,
+	addressType string,
+	address string,
+) (*RemoveFromAddressBookResponse, error) {
+	var resp RemoveFromAddressBookResponse
+	q := newQuery().
+		set("currency", currency).
+		set("type", addressType).
+		set("address", address)
+
+	err := s.client.request(ctx, urlPathRemoveFromAddressBook, q, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ## pageQuery has the currency, count and offset of the wallet history methods
+func pageQuery(currency string, count int, offset int) *query {
+	q := newQuery().set("currency", currency)
+	if count > 0 {
+		q.set("count", count)
+	}
+	if offset > 0 {
+		q.set("offset", offset)
+	}
+	return q
+}
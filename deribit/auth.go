@@ -0,0 +1,56 @@
+package deribit
+
+import (
+	"log/slog"
+)
+
+// redacted replaces secrets in logs
+const redacted = "[REDACTED]"
+
+type AuthResult struct {
+	AccessToken        string   `json:"access_token"`
+	EnabledFeatures    []string `json:"enabled_features"`
+	ExpiresIn          int      `json:"expires_in"`
+	GoogleLogin        bool     `json:"google_login"`
+	MandatoryTFAStatus string   `json:"mandatory_tfa_status"`
+	RefreshToken       string   `json:"refresh_token"`
+	Scope              string   `json:"scope"`
+	SID                string   `json:"sid,omitempty"`
+	State              string   `json:"state,omitempty"`
+	TokenType          string   `json:"token_type"`
+}
+
+// ## LogValue keeps the tokens out of logs
+func (r AuthResult) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("scope", r.Scope),
+		slog.Int("expires_in", r.ExpiresIn),
+		slog.String("token_type", r.TokenType),
+		slog.String("access_token", redacted),
+		slog.String("refresh_token", redacted),
+	)
+}
+
+type AuthRequest struct {
+	GrantType    string `json:"grant_type"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Timestamp    int64  `json:"timestamp,omitempty"`
+	Signature    string `json:"signature,omitempty"`
+	Nonce        string `json:"nonce,omitempty"`
+	Data         string `json:"data,omitempty"`
+	State        string `json:"state,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// ## LogValue keeps the client secret, refresh token and signature out of logs
+func (r AuthRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("grant_type", r.GrantType),
+		slog.String("client_id", r.ClientID),
+		slog.String("client_secret", redacted),
+		slog.String("refresh_token", redacted),
+		slog.String("signature", redacted),
+	)
+}
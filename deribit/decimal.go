@@ -0,0 +1,61 @@
+package deribit
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Decimal is a price, amount or trigger sent to Deribit. It is written with the shortest digits that read back
+// as the same value, never rounded to 6 decimals like %f and never in exponent form like 1e-07.
+type Decimal float64
+
+// ParseDecimal reads a decimal as written by String, an exponent is accepted too, NaN and infinities are not
+func ParseDecimal(s string) (Decimal, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid decimal %q: %w", s, err)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid decimal %q", s)
+	}
+	return Decimal(value), nil
+}
+
+// NewDecimal returns a pointer to value, e.g. for the Price of OrderRequest and EditRequest
+func NewDecimal(value float64) *Decimal {
+	d := Decimal(value)
+	return &d
+}
+
+func (d Decimal) Float64() float64 {
+	return float64(d)
+}
+
+func (d Decimal) String() string {
+	return strconv.FormatFloat(float64(d), 'f', -1, 64)
+}
+
+// ## MarshalJSON writes a JSON number without exponent, NaN and infinities have no JSON form
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(d)) || math.IsInf(float64(d), 0) {
+		return nil, fmt.Errorf("invalid decimal %v", float64(d))
+	}
+	return []byte(d.String()), nil
+}
+
+// ## UnmarshalJSON reads a JSON number or a number in a string
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if string(data) == "null" || len(data) == 0 {
+		return nil
+	}
+
+	value, err := ParseDecimal(string(data))
+	if err != nil {
+		return err
+	}
+	*d = value
+	return nil
+}
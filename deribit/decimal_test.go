@@ -0,0 +1,142 @@
+package deribit
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestDecimalString(t *testing.T) {
+	tests := []struct {
+		value Decimal
+		want  string
+	}{
+		{0, "0"},
+		{1e-7, "0.0000001"},
+		{0.00000001, "0.00000001"},
+		{1e21, "1000000000000000000000"},
+		{123456789.123456, "123456789.123456"},
+		{25000000.5, "25000000.5"},
+		{-0.0005, "-0.0005"},
+		{-1e-7, "-0.0000001"},
+		{-42000.25, "-42000.25"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.want {
+			t.Errorf("Decimal(%v).String() = %q, want %q", float64(tt.value), got, tt.want)
+		}
+
+		data, err := json.Marshal(tt.value)
+		if err != nil {
+			t.Fatalf("json.Marshal(%v): %v", float64(tt.value), err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("json.Marshal(%v) = %s, want %s", float64(tt.value), data, tt.want)
+		}
+
+		parsed, err := ParseDecimal(tt.want)
+		if err != nil {
+			t.Fatalf("ParseDecimal(%q): %v", tt.want, err)
+		}
+		if parsed != tt.value {
+			t.Errorf("ParseDecimal(%q) = %v, want %v", tt.want, float64(parsed), float64(tt.value))
+		}
+
+		var decoded Decimal
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", data, err)
+		}
+		if decoded != tt.value {
+			t.Errorf("json.Unmarshal(%s) = %v, want %v", data, float64(decoded), float64(tt.value))
+		}
+	}
+}
+
+func TestDecimalMarshalJSONInBody(t *testing.T) {
+	request := OrderRequest{
+		InstrumentName: "BTC-PERPETUAL",
+		Amount:         0.00000001,
+		Price:          NewDecimal(-12.5),
+		TriggerPrice:   1e21,
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"instrument_name":"BTC-PERPETUAL","amount":0.00000001,"price":-12.5,"trigger_price":1000000000000000000000}`
+	if string(data) != want {
+		t.Errorf("json.Marshal = %s, want %s", data, want)
+	}
+}
+
+func TestDecimalMarshalJSONRejectsNaNAndInf(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if data, err := json.Marshal(Decimal(value)); err == nil {
+			t.Errorf("json.Marshal(%v) = %s, want an error", value, data)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Decimal
+	}{
+		{"1e-7", 1e-7},
+		{"1E21", 1e21},
+		{"-0.00000001", -0.00000001},
+		{"99999999999.99", 99999999999.99},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDecimal(tt.input)
+		if err != nil {
+			t.Fatalf("ParseDecimal(%q): %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseDecimal(%q) = %v, want %v", tt.input, float64(got), float64(tt.want))
+		}
+	}
+
+	for _, input := range []string{"", "abc", "1.2.3", "NaN", "Inf", "-Inf", "+Infinity"} {
+		if got, err := ParseDecimal(input); err == nil {
+			t.Errorf("ParseDecimal(%q) = %v, want an error", input, float64(got))
+		}
+	}
+}
+
+func TestDecimalUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Decimal
+	}{
+		{`0.0000001`, 1e-7},
+		{`1e-7`, 1e-7},
+		{`"0.00000001"`, 0.00000001},
+		{`"-42000.25"`, -42000.25},
+		{`"1000000000000000000000"`, 1e21},
+		{`1e21`, 1e21},
+		{`null`, 0},
+		{`""`, 0},
+	}
+
+	for _, tt := range tests {
+		var got Decimal
+		if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+			t.Fatalf("json.Unmarshal(%s): %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("json.Unmarshal(%s) = %v, want %v", tt.input, float64(got), float64(tt.want))
+		}
+	}
+
+	for _, input := range []string{`"abc"`, `"NaN"`, `"Inf"`, `true`} {
+		var got Decimal
+		if err := json.Unmarshal([]byte(input), &got); err == nil {
+			t.Errorf("json.Unmarshal(%s) = %v, want an error", input, float64(got))
+		}
+	}
+}
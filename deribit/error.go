@@ -0,0 +1,113 @@
+package deribit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ## Deribit JSON-RPC error codes
+const (
+	CodeOrderNotFound      = 10004
+	CodeNotEnoughFunds     = 10009
+	CodeTooManyRequests    = 10028
+	CodeRetry              = 10040
+	CodePriceWrongTick     = 10043
+	CodeInvalidCredentials = 13004
+	CodeUnauthorized       = 13009
+)
+
+// ## Sentinels for errors.Is, only the code is compared
+var (
+	ErrOrderNotFound      = &Error{Code: CodeOrderNotFound, Message: "order_not_found"}
+	ErrNotEnoughFunds     = &Error{Code: CodeNotEnoughFunds, Message: "not_enough_funds"}
+	ErrTooManyRequests    = &Error{Code: CodeTooManyRequests, Message: "too_many_requests"}
+	ErrRetry              = &Error{Code: CodeRetry, Message: "retry"}
+	ErrPriceWrongTick     = &Error{Code: CodePriceWrongTick, Message: "price_wrong_tick"}
+	ErrInvalidCredentials = &Error{Code: CodeInvalidCredentials, Message: "invalid_credentials"}
+	ErrUnauthorized       = &Error{Code: CodeUnauthorized, Message: "unauthorized"}
+)
+
+// ErrorData is the data of a Deribit error, Reason and Param are set for invalid parameters
+type ErrorData struct {
+	Reason string
+	Param  string
+	// Raw is the data as received, it is not always an object
+	Raw json.RawMessage
+}
+
+func (d *ErrorData) UnmarshalJSON(data []byte) error {
+	d.Raw = append(json.RawMessage(nil), data...)
+
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+
+	var fields struct {
+		Reason string `json:"reason"`
+		Param  string `json:"param"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+
+	d.Reason = fields.Reason
+	d.Param = fields.Param
+	return nil
+}
+
+func (d ErrorData) MarshalJSON() ([]byte, error) {
+	if len(d.Raw) > 0 {
+		return d.Raw, nil
+	}
+	return []byte("null"), nil
+}
+
+// Error is the JSON-RPC error object returned by Deribit, the api and ws clients return it alike
+type Error struct {
+	Code    int       `json:"code"`
+	Message string    `json:"message"`
+	Data    ErrorData `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("request failed: code: %d, message: %s", e.Code, e.Message)
+	if e.Data.Param != "" {
+		msg += fmt.Sprintf(", param: %s", e.Data.Param)
+	}
+	if e.Data.Reason != "" {
+		msg += fmt.Sprintf(", reason: %s", e.Data.Reason)
+	}
+	return msg
+}
+
+// ## Is matches any *Error with the same code, e.g. errors.Is(err, ErrTooManyRequests)
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ## ErrorCode returns the Deribit code of err, 0 when err is not a Deribit error
+func ErrorCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
+}
+
+func IsRateLimited(err error) bool {
+	return errors.Is(err, ErrTooManyRequests)
+}
+
+func IsInsufficientFunds(err error) bool {
+	return errors.Is(err, ErrNotEnoughFunds)
+}
+
+func IsOrderNotFound(err error) bool {
+	return errors.Is(err, ErrOrderNotFound)
+}
+
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrInvalidCredentials)
+}
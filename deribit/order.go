@@ -0,0 +1,395 @@
+package deribit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidOrder is wrapped by every validation error of OrderRequest
+var ErrInvalidOrder = errors.New("invalid order")
+
+const (
+	OrderTypeLimit        = "limit"
+	OrderTypeMarket       = "market"
+	OrderTypeStopLimit    = "stop_limit"
+	OrderTypeStopMarket   = "stop_market"
+	OrderTypeTakeLimit    = "take_limit"
+	OrderTypeTakeMarket   = "take_market"
+	OrderTypeMarketLimit  = "market_limit"
+	OrderTypeTrailingStop = "trailing_stop"
+
+	TimeInForceGoodTilCancelled  = "good_til_cancelled"
+	TimeInForceGoodTilDay        = "good_til_day"
+	TimeInForceFillOrKill        = "fill_or_kill"
+	TimeInForceImmediateOrCancel = "immediate_or_cancel"
+
+	LinkedOrderTypeOTO   = "one_triggers_other"
+	LinkedOrderTypeOCO   = "one_cancels_other"
+	LinkedOrderTypeOTOCO = "one_triggers_one_cancels_other"
+)
+
+// OTOCOConfig is one linked order of otoco_config, Price is a pointer like in OrderRequest
+type OTOCOConfig struct {
+	Amount         Decimal  `json:"amount,omitempty"`
+	Direction      string   `json:"direction"`
+	Type           string   `json:"type,omitempty"`
+	Label          string   `json:"label,omitempty"`
+	Price          *Decimal `json:"price,omitempty"`
+	ReduceOnly     bool     `json:"reduce_only,omitempty"`
+	TimeInForce    string   `json:"time_in_force,omitempty"`
+	PostOnly       bool     `json:"post_only,omitempty"`
+	RejectPostOnly bool     `json:"reject_post_only,omitempty"`
+	TriggerPrice   Decimal  `json:"trigger_price,omitempty"`
+	TriggerOffset  Decimal  `json:"trigger_offset,omitempty"`
+	Trigger        string   `json:"trigger,omitempty"`
+}
+
+// OrderRequest is an order of the buy and sell endpoints. Price is a pointer because spreads and combos trade
+// at zero or negative prices, nil sends no price.
+type OrderRequest struct {
+	InstrumentName       string        `json:"instrument_name"`
+	Amount               Decimal       `json:"amount,omitempty"`
+	Contracts            int64         `json:"contracts,omitempty"`
+	Type                 string        `json:"type,omitempty"`
+	Label                string        `json:"label,omitempty"`
+	Price                *Decimal      `json:"price,omitempty"`
+	TimeInForce          string        `json:"time_in_force,omitempty"`
+	MaxShow              int64         `json:"max_show,omitempty"`
+	PostOnly             bool          `json:"post_only,omitempty"`
+	RejectPostOnly       bool          `json:"reject_post_only,omitempty"`
+	ReduceOnly           bool          `json:"reduce_only,omitempty"`
+	TriggerPrice         Decimal       `json:"trigger_price,omitempty"`
+	TriggerOffset        Decimal       `json:"trigger_offset,omitempty"`
+	Trigger              string        `json:"trigger,omitempty"`
+	Advanced             string        `json:"advanced,omitempty"`
+	MMP                  bool          `json:"mmp,omitempty"`
+	ValidUntil           int64         `json:"valid_until,omitempty"`
+	LinkedOrderType      string        `json:"linked_order_type,omitempty"`
+	TriggerFillCondition string        `json:"trigger_fill_condition,omitempty"`
+	OTOCOConfig          []OTOCOConfig `json:"otoco_config,omitempty"`
+}
+
+// EditRequest amends a resting order in place and keeps its queue priority when only the amount is reduced,
+// OrderID is used by private/edit and Label with InstrumentName by private/edit_by_label.
+// PostOnly and ReduceOnly are pointers so they can also be turned off, nil keeps Deribit's default.
+// Price is a pointer like in OrderRequest, a zero or negative price is valid for spreads and combos.
+type EditRequest struct {
+	OrderID        string   `json:"order_id,omitempty"`
+	Label          string   `json:"label,omitempty"`
+	InstrumentName string   `json:"instrument_name,omitempty"`
+	Amount         Decimal  `json:"amount,omitempty"`
+	Contracts      int64    `json:"contracts,omitempty"`
+	Price          *Decimal `json:"price,omitempty"`
+	PostOnly       *bool    `json:"post_only,omitempty"`
+	RejectPostOnly bool     `json:"reject_post_only,omitempty"`
+	ReduceOnly     *bool    `json:"reduce_only,omitempty"`
+	Advanced       string   `json:"advanced,omitempty"`
+	TriggerPrice   Decimal  `json:"trigger_price,omitempty"`
+	TriggerOffset  Decimal  `json:"trigger_offset,omitempty"`
+	MMP            bool     `json:"mmp,omitempty"`
+	ValidUntil     int64    `json:"valid_until,omitempty"`
+}
+
+type OrderResultOrderResponse struct {
+	Quote                 bool     `json:"quote"`
+	Triggered             bool     `json:"triggered"`
+	Mobile                bool     `json:"mobile,omitempty"`
+	AppName               string   `json:"app_name,omitempty"`
+	Implv                 float64  `json:"implv,omitempty"`
+	USD                   float64  `json:"usd,omitempty"`
+	OtoOrderIds           []string `json:"oto_order_ids"`
+	API                   bool     `json:"api"`
+	AveragePrice          float64  `json:"average_price"`
+	Advanced              string   `json:"advanced,omitempty"`
+	OrderID               string   `json:"order_id"`
+	PostOnly              bool     `json:"post_only"`
+	FilledAmount          float64  `json:"filled_amount"`
+	Trigger               string   `json:"trigger,omitempty"`
+	TriggerOrderID        string   `json:"trigger_order_id,omitempty"`
+	Direction             string   `json:"direction"`
+	Contracts             float64  `json:"contracts,omitempty"`
+	IsSecondaryOto        bool     `json:"is_secondary_oto,omitempty"`
+	Replaced              bool     `json:"replaced"`
+	MMPGroup              string   `json:"mmp_group,omitempty"`
+	MMP                   bool     `json:"mmp"`
+	LastUpdateTimestamp   int64    `json:"last_update_timestamp"`
+	CreationTimestamp     int64    `json:"creation_timestamp"`
+	CancelReason          string   `json:"cancel_reason,omitempty"`
+	MMPCancelled          bool     `json:"mmp_cancelled,omitempty"`
+	QuoteID               string   `json:"quote_id,omitempty"`
+	OrderState            string   `json:"order_state"`
+	IsRebalance           bool     `json:"is_rebalance,omitempty"`
+	RejectPostOnly        bool     `json:"reject_post_only,omitempty"`
+	Label                 string   `json:"label,omitempty"`
+	IsLiquidation         bool     `json:"is_liquidation,omitempty"`
+	Price                 float64  `json:"price"`
+	Web                   bool     `json:"web,omitempty"`
+	TimeInForce           string   `json:"time_in_force"`
+	TriggerReferencePrice float64  `json:"trigger_reference_price,omitempty"`
+	OrderType             string   `json:"order_type"`
+	IsPrimaryOtoco        bool     `json:"is_primary_otoco,omitempty"`
+	OriginalOrderType     string   `json:"original_order_type,omitempty"`
+	BlockTrade            bool     `json:"block_trade,omitempty"`
+	TriggerPrice          float64  `json:"trigger_price,omitempty"`
+	OcoRef                string   `json:"oco_ref,omitempty"`
+	TriggerOffset         float64  `json:"trigger_offset,omitempty"`
+	QuoteSetID            string   `json:"quote_set_id,omitempty"`
+	AutoReplaced          bool     `json:"auto_replaced,omitempty"`
+	ReduceOnly            bool     `json:"reduce_only,omitempty"`
+	MaxShow               float64  `json:"max_show,omitempty"`
+	Amount                float64  `json:"amount"`
+	RiskReducing          bool     `json:"risk_reducing,omitempty"`
+	InstrumentName        string   `json:"instrument_name"`
+	TriggerFillCondition  string   `json:"trigger_fill_condition,omitempty"`
+	PrimaryOrderID        string   `json:"primary_order_id,omitempty"`
+}
+
+type OrderResultTradeResponse struct {
+	TradeID         string                     `json:"trade_id"`
+	TickDirection   int                        `json:"tick_direction"`
+	FeeCurrency     string                     `json:"fee_currency"`
+	API             bool                       `json:"api"`
+	Advanced        string                     `json:"advanced,omitempty"`
+	OrderID         string                     `json:"order_id"`
+	Liquidity       string                     `json:"liquidity"`
+	PostOnly        bool                       `json:"post_only"`
+	Direction       string                     `json:"direction"`
+	Contracts       float64                    `json:"contracts,omitempty"`
+	MMP             bool                       `json:"mmp"`
+	Fee             float64                    `json:"fee"`
+	QuoteID         string                     `json:"quote_id,omitempty"`
+	IndexPrice      float64                    `json:"index_price"`
+	Label           string                     `json:"label,omitempty"`
+	BlockTradeID    string                     `json:"block_trade_id,omitempty"`
+	Price           float64                    `json:"price"`
+	ComboID         string                     `json:"combo_id,omitempty"`
+	MatchingID      string                     `json:"matching_id"`
+	OrderType       string                     `json:"order_type"`
+	ProfitLoss      float64                    `json:"profit_loss"`
+	Timestamp       int64                      `json:"timestamp"`
+	IV              float64                    `json:"iv,omitempty"`
+	State           string                     `json:"state"`
+	UnderlyingPrice float64                    `json:"underlying_price,omitempty"`
+	QuoteSetID      string                     `json:"quote_set_id,omitempty"`
+	MarkPrice       float64                    `json:"mark_price"`
+	BlockRFQID      int                        `json:"block_rfq_id,omitempty"`
+	ComboTradeID    int                        `json:"combo_trade_id,omitempty"`
+	ReduceOnly      bool                       `json:"reduce_only"`
+	Amount          float64                    `json:"amount"`
+	Liquidation     string                     `json:"liquidation,omitempty"`
+	TradeSeq        int                        `json:"trade_seq"`
+	RiskReducing    bool                       `json:"risk_reducing"`
+	InstrumentName  string                     `json:"instrument_name"`
+	Legs            []OrderResultTradeResponse `json:"legs,omitempty"`
+}
+
+type OrderResultResponse struct {
+	Order  OrderResultOrderResponse   `json:"order"`
+	Trades []OrderResultTradeResponse `json:"trades"`
+}
+
+// CancelReport is one execution report of a detailed cancel, the cancelled orders of one instrument and order type
+type CancelReport struct {
+	Currency       string                     `json:"currency"`
+	InstrumentName string                     `json:"instrument_name"`
+	Type           string                     `json:"type"`
+	Result         []OrderResultOrderResponse `json:"result"`
+}
+
+// CancelResult is the number of cancelled orders, with the reports when the cancel was detailed
+type CancelResult struct {
+	Count   int
+	Reports []CancelReport
+}
+
+func (r *CancelResult) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var reports []CancelReport
+		if err := json.Unmarshal(data, &reports); err != nil {
+			return err
+		}
+
+		r.Reports = reports
+		r.Count = 0
+		for _, report := range reports {
+			r.Count += len(report.Result)
+		}
+		return nil
+	}
+
+	r.Reports = nil
+	return json.Unmarshal(data, &r.Count)
+}
+
+// CancelQuotesRequest selects the quotes to cancel by CancelType:
+// delta (MinDelta, MaxDelta), quote_set_id, instrument, instrument_kind (Kind), currency or all
+type CancelQuotesRequest struct {
+	CancelType     string  `json:"cancel_type"`
+	MinDelta       float64 `json:"min_delta,omitempty"`
+	MaxDelta       float64 `json:"max_delta,omitempty"`
+	QuoteSetID     string  `json:"quote_set_id,omitempty"`
+	InstrumentName string  `json:"instrument_name,omitempty"`
+	Kind           string  `json:"kind_name,omitempty"`
+	Currency       string  `json:"currency,omitempty"`
+	Detailed       bool    `json:"detailed,omitempty"`
+	FreezeQuotes   bool    `json:"freeze_quotes,omitempty"`
+}
+
+// ## Validate checks the fields that Deribit rejects together, before any network call
+func (r *OrderRequest) Validate() error {
+	if r.InstrumentName == "" {
+		return fmt.Errorf("%w: instrument_name is required", ErrInvalidOrder)
+	}
+
+	if r.Amount != 0 && r.Contracts != 0 {
+		return fmt.Errorf("%w: amount and contracts are mutually exclusive", ErrInvalidOrder)
+	}
+	if r.Amount == 0 && r.Contracts == 0 {
+		return fmt.Errorf("%w: amount or contracts is required", ErrInvalidOrder)
+	}
+	if r.Amount < 0 || r.Contracts < 0 {
+		return fmt.Errorf("%w: amount and contracts must be positive", ErrInvalidOrder)
+	}
+
+	orderType := r.Type
+	if orderType == "" {
+		orderType = OrderTypeLimit
+	}
+
+	switch orderType {
+	case OrderTypeLimit, OrderTypeStopLimit, OrderTypeTakeLimit:
+		if r.Price == nil {
+			return fmt.Errorf("%w: price is required for %s order", ErrInvalidOrder, orderType)
+		}
+	case OrderTypeMarket, OrderTypeStopMarket, OrderTypeTakeMarket, OrderTypeMarketLimit, OrderTypeTrailingStop:
+		if r.PostOnly || r.RejectPostOnly {
+			return fmt.Errorf("%w: post_only is only allowed for limit orders", ErrInvalidOrder)
+		}
+	default:
+		return fmt.Errorf("%w: unknown order type %q", ErrInvalidOrder, r.Type)
+	}
+
+	if r.RejectPostOnly && !r.PostOnly {
+		return fmt.Errorf("%w: reject_post_only requires post_only", ErrInvalidOrder)
+	}
+
+	switch r.TimeInForce {
+	case "", TimeInForceGoodTilCancelled, TimeInForceGoodTilDay, TimeInForceFillOrKill, TimeInForceImmediateOrCancel:
+	default:
+		return fmt.Errorf("%w: unknown time_in_force %q", ErrInvalidOrder, r.TimeInForce)
+	}
+
+	if err := validateTrigger(orderType, r.Trigger, r.TriggerPrice, r.TriggerOffset); err != nil {
+		return err
+	}
+
+	if r.LinkedOrderType == "" {
+		if len(r.OTOCOConfig) > 0 {
+			return fmt.Errorf("%w: otoco_config requires linked_order_type", ErrInvalidOrder)
+		}
+		if r.TriggerFillCondition != "" {
+			return fmt.Errorf("%w: trigger_fill_condition requires linked_order_type", ErrInvalidOrder)
+		}
+		return nil
+	}
+
+	switch r.LinkedOrderType {
+	case LinkedOrderTypeOTO, LinkedOrderTypeOCO, LinkedOrderTypeOTOCO:
+	default:
+		return fmt.Errorf("%w: unknown linked_order_type %q", ErrInvalidOrder, r.LinkedOrderType)
+	}
+	if len(r.OTOCOConfig) == 0 {
+		return fmt.Errorf("%w: linked_order_type %s requires otoco_config", ErrInvalidOrder, r.LinkedOrderType)
+	}
+
+	for i, config := range r.OTOCOConfig {
+		if config.Direction != "buy" && config.Direction != "sell" {
+			return fmt.Errorf("%w: otoco_config[%d] direction must be buy or sell", ErrInvalidOrder, i)
+		}
+
+		configType := config.Type
+		if configType == "" {
+			configType = OrderTypeLimit
+		}
+		switch configType {
+		case OrderTypeLimit, OrderTypeStopLimit, OrderTypeTakeLimit:
+			if config.Price == nil {
+				return fmt.Errorf("%w: otoco_config[%d] price is required for %s order", ErrInvalidOrder, i, configType)
+			}
+		}
+		if err := validateTrigger(configType, config.Trigger, config.TriggerPrice, config.TriggerOffset); err != nil {
+			return fmt.Errorf("otoco_config[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ## Validate checks the edit fields, order_id or label is checked by Edit and EditByLabel
+func (r *EditRequest) Validate() error {
+	if r.Amount != 0 && r.Contracts != 0 {
+		return fmt.Errorf("%w: amount and contracts are mutually exclusive", ErrInvalidOrder)
+	}
+	if r.Amount == 0 && r.Contracts == 0 {
+		return fmt.Errorf("%w: amount or contracts is required", ErrInvalidOrder)
+	}
+	if r.Amount < 0 || r.Contracts < 0 {
+		return fmt.Errorf("%w: amount and contracts must be positive", ErrInvalidOrder)
+	}
+
+	switch r.Advanced {
+	case "":
+	case "implv", "usd":
+		if r.Price == nil {
+			return fmt.Errorf("%w: advanced %s requires price", ErrInvalidOrder, r.Advanced)
+		}
+	default:
+		return fmt.Errorf("%w: unknown advanced %q, expected implv or usd", ErrInvalidOrder, r.Advanced)
+	}
+
+	if r.RejectPostOnly && (r.PostOnly == nil || !*r.PostOnly) {
+		return fmt.Errorf("%w: reject_post_only requires post_only", ErrInvalidOrder)
+	}
+	if r.TriggerPrice != 0 && r.TriggerOffset != 0 {
+		return fmt.Errorf("%w: trigger_price and trigger_offset are mutually exclusive", ErrInvalidOrder)
+	}
+
+	return nil
+}
+
+// ## Trigger fields are only for stop, take and trailing orders and required there
+func validateTrigger(orderType, trigger string, triggerPrice, triggerOffset Decimal) error {
+	isTriggered := strings.HasPrefix(orderType, "stop_") ||
+		strings.HasPrefix(orderType, "take_") ||
+		orderType == OrderTypeTrailingStop
+
+	if !isTriggered {
+		if trigger != "" || triggerPrice != 0 || triggerOffset != 0 {
+			return fmt.Errorf("%w: trigger fields are only allowed for stop, take and trailing_stop orders", ErrInvalidOrder)
+		}
+		return nil
+	}
+
+	switch trigger {
+	case "index_price", "mark_price", "last_price":
+	case "":
+		return fmt.Errorf("%w: trigger is required for %s order", ErrInvalidOrder, orderType)
+	default:
+		return fmt.Errorf("%w: unknown trigger %q", ErrInvalidOrder, trigger)
+	}
+
+	if orderType == OrderTypeTrailingStop {
+		if triggerOffset == 0 {
+			return fmt.Errorf("%w: trigger_offset is required for trailing_stop order", ErrInvalidOrder)
+		}
+		return nil
+	}
+
+	if triggerPrice <= 0 {
+		return fmt.Errorf("%w: trigger_price is required for %s order", ErrInvalidOrder, orderType)
+	}
+
+	return nil
+}
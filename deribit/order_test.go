@@ -0,0 +1,293 @@
+package deribit
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestOrderRequestValidate(t *testing.T) {
+	limit := func() OrderRequest {
+		return OrderRequest{InstrumentName: "BTC-PERPETUAL", Amount: 10, Price: NewDecimal(65000)}
+	}
+
+	tests := []struct {
+		name    string
+		request func() OrderRequest
+		wantErr bool
+	}{
+		{"limit", limit, false},
+		{"limit at a zero price", func() OrderRequest {
+			r := limit()
+			r.Price = NewDecimal(0)
+			return r
+		}, false},
+		{"limit at a negative price", func() OrderRequest {
+			r := limit()
+			r.Price = NewDecimal(-12.5)
+			return r
+		}, false},
+		{"limit without price", func() OrderRequest {
+			r := limit()
+			r.Price = nil
+			return r
+		}, true},
+		{"no instrument", func() OrderRequest {
+			r := limit()
+			r.InstrumentName = ""
+			return r
+		}, true},
+		{"no amount", func() OrderRequest {
+			r := limit()
+			r.Amount = 0
+			return r
+		}, true},
+		{"amount and contracts", func() OrderRequest {
+			r := limit()
+			r.Contracts = 1
+			return r
+		}, true},
+		{"contracts", func() OrderRequest {
+			r := limit()
+			r.Amount, r.Contracts = 0, 1
+			return r
+		}, false},
+		{"negative amount", func() OrderRequest {
+			r := limit()
+			r.Amount = -10
+			return r
+		}, true},
+		{"unknown type", func() OrderRequest {
+			r := limit()
+			r.Type = "iceberg"
+			return r
+		}, true},
+		{"market", func() OrderRequest {
+			return OrderRequest{InstrumentName: "BTC-PERPETUAL", Amount: 10, Type: OrderTypeMarket}
+		}, false},
+		{"post_only market", func() OrderRequest {
+			return OrderRequest{InstrumentName: "BTC-PERPETUAL", Amount: 10, Type: OrderTypeMarket, PostOnly: true}
+		}, true},
+		{"reject_post_only without post_only", func() OrderRequest {
+			r := limit()
+			r.RejectPostOnly = true
+			return r
+		}, true},
+		{"reject_post_only with post_only", func() OrderRequest {
+			r := limit()
+			r.PostOnly, r.RejectPostOnly = true, true
+			return r
+		}, false},
+		{"unknown time_in_force", func() OrderRequest {
+			r := limit()
+			r.TimeInForce = "good_til_tomorrow"
+			return r
+		}, true},
+		{"stop_limit", func() OrderRequest {
+			r := limit()
+			r.Type, r.TriggerPrice, r.Trigger = OrderTypeStopLimit, 64000, "mark_price"
+			return r
+		}, false},
+		{"stop_limit without price", func() OrderRequest {
+			r := limit()
+			r.Type, r.TriggerPrice, r.Trigger, r.Price = OrderTypeStopLimit, 64000, "mark_price", nil
+			return r
+		}, true},
+		{"otoco_config without linked_order_type", func() OrderRequest {
+			r := limit()
+			r.OTOCOConfig = []OTOCOConfig{{Direction: "sell", Price: NewDecimal(66000)}}
+			return r
+		}, true},
+		{"trigger_fill_condition without linked_order_type", func() OrderRequest {
+			r := limit()
+			r.TriggerFillCondition = "first_hit"
+			return r
+		}, true},
+		{"linked_order_type without otoco_config", func() OrderRequest {
+			r := limit()
+			r.LinkedOrderType = LinkedOrderTypeOTO
+			return r
+		}, true},
+		{"unknown linked_order_type", func() OrderRequest {
+			r := limit()
+			r.LinkedOrderType = "one_triggers_all"
+			r.OTOCOConfig = []OTOCOConfig{{Direction: "sell", Price: NewDecimal(66000)}}
+			return r
+		}, true},
+		{"otoco", func() OrderRequest {
+			r := limit()
+			r.LinkedOrderType = LinkedOrderTypeOTOCO
+			r.OTOCOConfig = []OTOCOConfig{
+				{Direction: "sell", Price: NewDecimal(0)},
+				{Direction: "sell", Type: OrderTypeStopMarket, TriggerPrice: 64000, Trigger: "index_price"},
+			}
+			return r
+		}, false},
+		{"otoco limit without price", func() OrderRequest {
+			r := limit()
+			r.LinkedOrderType = LinkedOrderTypeOTO
+			r.OTOCOConfig = []OTOCOConfig{{Direction: "sell"}}
+			return r
+		}, true},
+		{"otoco take_limit without price", func() OrderRequest {
+			r := limit()
+			r.LinkedOrderType = LinkedOrderTypeOTO
+			r.OTOCOConfig = []OTOCOConfig{{Direction: "sell", Type: OrderTypeTakeLimit, TriggerPrice: 70000, Trigger: "mark_price"}}
+			return r
+		}, true},
+		{"otoco without direction", func() OrderRequest {
+			r := limit()
+			r.LinkedOrderType = LinkedOrderTypeOTO
+			r.OTOCOConfig = []OTOCOConfig{{Price: NewDecimal(66000)}}
+			return r
+		}, true},
+		{"otoco stop_market without trigger", func() OrderRequest {
+			r := limit()
+			r.LinkedOrderType = LinkedOrderTypeOTO
+			r.OTOCOConfig = []OTOCOConfig{{Direction: "sell", Type: OrderTypeStopMarket, TriggerPrice: 64000}}
+			return r
+		}, true},
+	}
+
+	for _, tt := range tests {
+		request := tt.request()
+		err := request.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidOrder", tt.name, err)
+		}
+	}
+}
+
+func TestEditRequestValidate(t *testing.T) {
+	yes := true
+
+	tests := []struct {
+		name    string
+		request EditRequest
+		wantErr bool
+	}{
+		{"amount and price", EditRequest{OrderID: "ETH-1", Amount: 10, Price: NewDecimal(3000)}, false},
+		{"zero price", EditRequest{OrderID: "ETH-1", Amount: 10, Price: NewDecimal(0)}, false},
+		{"negative price", EditRequest{OrderID: "ETH-1", Amount: 10, Price: NewDecimal(-1.5)}, false},
+		{"no amount", EditRequest{OrderID: "ETH-1", Price: NewDecimal(3000)}, true},
+		{"amount and contracts", EditRequest{OrderID: "ETH-1", Amount: 10, Contracts: 1}, true},
+		{"negative contracts", EditRequest{OrderID: "ETH-1", Contracts: -1}, true},
+		{"advanced with price", EditRequest{OrderID: "ETH-1", Amount: 1, Advanced: "implv", Price: NewDecimal(55)}, false},
+		{"advanced without price", EditRequest{OrderID: "ETH-1", Amount: 1, Advanced: "usd"}, true},
+		{"unknown advanced", EditRequest{OrderID: "ETH-1", Amount: 1, Advanced: "btc", Price: NewDecimal(1)}, true},
+		{"reject_post_only without post_only", EditRequest{OrderID: "ETH-1", Amount: 1, RejectPostOnly: true}, true},
+		{"reject_post_only with post_only", EditRequest{OrderID: "ETH-1", Amount: 1, RejectPostOnly: true, PostOnly: &yes}, false},
+		{"trigger_price and trigger_offset", EditRequest{OrderID: "ETH-1", Amount: 1, TriggerPrice: 10, TriggerOffset: 5}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.request.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidOrder", tt.name, err)
+		}
+	}
+}
+
+func TestValidateTrigger(t *testing.T) {
+	tests := []struct {
+		name          string
+		orderType     string
+		trigger       string
+		triggerPrice  Decimal
+		triggerOffset Decimal
+		wantErr       bool
+	}{
+		{"limit without trigger", OrderTypeLimit, "", 0, 0, false},
+		{"limit with trigger", OrderTypeLimit, "mark_price", 0, 0, true},
+		{"market with trigger_price", OrderTypeMarket, "", 100, 0, true},
+		{"market with trigger_offset", OrderTypeMarket, "", 0, 5, true},
+		{"stop_limit", OrderTypeStopLimit, "index_price", 100, 0, false},
+		{"stop_market", OrderTypeStopMarket, "last_price", 100, 0, false},
+		{"take_limit", OrderTypeTakeLimit, "mark_price", 100, 0, false},
+		{"take_market", OrderTypeTakeMarket, "mark_price", 100, 0, false},
+		{"stop without trigger", OrderTypeStopMarket, "", 100, 0, true},
+		{"stop with unknown trigger", OrderTypeStopMarket, "best_bid", 100, 0, true},
+		{"stop without trigger_price", OrderTypeStopMarket, "mark_price", 0, 0, true},
+		{"take with negative trigger_price", OrderTypeTakeMarket, "mark_price", -1, 0, true},
+		{"trailing_stop", OrderTypeTrailingStop, "mark_price", 0, 50, false},
+		{"trailing_stop without trigger_offset", OrderTypeTrailingStop, "mark_price", 0, 0, true},
+		{"trailing_stop without trigger", OrderTypeTrailingStop, "", 0, 50, true},
+	}
+
+	for _, tt := range tests {
+		err := validateTrigger(tt.orderType, tt.trigger, tt.triggerPrice, tt.triggerOffset)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateTrigger() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("%s: validateTrigger() = %v, want ErrInvalidOrder", tt.name, err)
+		}
+	}
+}
+
+func TestCancelResultUnmarshalJSON(t *testing.T) {
+	t.Run("count", func(t *testing.T) {
+		var response struct {
+			Result CancelResult `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(`{"jsonrpc":"2.0","id":7,"result":3}`), &response); err != nil {
+			t.Fatal(err)
+		}
+		if response.Result.Count != 3 || response.Result.Reports != nil {
+			t.Errorf("result = %+v, want a count of 3 without reports", response.Result)
+		}
+	})
+
+	t.Run("detailed", func(t *testing.T) {
+		data := `[
+			{"currency":"BTC","instrument_name":"BTC-PERPETUAL","type":"limit","result":[
+				{"order_id":"BTC-1","instrument_name":"BTC-PERPETUAL","order_state":"cancelled"},
+				{"order_id":"BTC-2","instrument_name":"BTC-PERPETUAL","order_state":"cancelled"}
+			]},
+			{"currency":"BTC","instrument_name":"BTC-27DEC24","type":"trigger","result":[
+				{"order_id":"BTC-3","instrument_name":"BTC-27DEC24","order_state":"untriggered"}
+			]},
+			{"currency":"ETH","instrument_name":"ETH-PERPETUAL","type":"limit","result":[]}
+		]`
+
+		var result CancelResult
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Count != 3 {
+			t.Errorf("count = %d, want 3", result.Count)
+		}
+		if len(result.Reports) != 3 {
+			t.Fatalf("got %d reports, want 3", len(result.Reports))
+		}
+		report := result.Reports[1]
+		if report.InstrumentName != "BTC-27DEC24" || report.Type != "trigger" || len(report.Result) != 1 || report.Result[0].OrderID != "BTC-3" {
+			t.Errorf("report = %+v, want the trigger order BTC-3 of BTC-27DEC24", report)
+		}
+	})
+
+	t.Run("count after detailed", func(t *testing.T) {
+		result := CancelResult{Count: 5, Reports: []CancelReport{{Currency: "BTC"}}}
+		if err := json.Unmarshal([]byte(`0`), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Count != 0 || result.Reports != nil {
+			t.Errorf("result = %+v, want a count of 0 without the old reports", result)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, data := range []string{`"3"`, `1.5`, `{"count":3}`, `[1,2]`, `[{"result":"x"}]`, `true`} {
+			var result CancelResult
+			if err := json.Unmarshal([]byte(data), &result); err == nil {
+				t.Errorf("json.Unmarshal(%s) = %+v, want an error", data, result)
+			}
+		}
+	})
+}
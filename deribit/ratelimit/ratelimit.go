@@ -0,0 +1,236 @@
+// Package ratelimit throttles Deribit requests with the credit model of the exchange:
+// every request costs credits from the pool of its class and the pools refill at a fixed rate.
+// One Limiter can be shared by the api and ws clients of the same account.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by Wait in fail fast mode when the pool has not enough credits
+var ErrRateLimited = errors.New("rate limited")
+
+// Class is the credit pool of a method
+type Class int
+
+const (
+	NonMatchingEngine Class = iota
+	MatchingEngine
+	classCount
+)
+
+func (c Class) String() string {
+	if c == MatchingEngine {
+		return "matching_engine"
+	}
+	return "non_matching_engine"
+}
+
+// ## Methods that go through the matching engine, everything else uses the non-ME pool
+var matchingEngineMethods = map[string]struct{}{
+	"private/buy":                         {},
+	"private/sell":                        {},
+	"private/edit":                        {},
+	"private/edit_by_label":               {},
+	"private/close_position":              {},
+	"private/mass_quote":                  {},
+	"private/cancel":                      {},
+	"private/cancel_all":                  {},
+	"private/cancel_all_by_currency":      {},
+	"private/cancel_all_by_currency_pair": {},
+	"private/cancel_all_by_instrument":    {},
+	"private/cancel_all_by_kind_or_type":  {},
+	"private/cancel_by_label":             {},
+	"private/cancel_quotes":               {},
+}
+
+// ## Classify returns the pool of a JSON-RPC method, with or without the /api/v2/ prefix
+func Classify(method string) Class {
+	method = strings.TrimPrefix(method, "/api/v2")
+	method = strings.TrimPrefix(method, "/")
+
+	if _, ok := matchingEngineMethods[method]; ok {
+		return MatchingEngine
+	}
+	return NonMatchingEngine
+}
+
+// Pool is the credit configuration of one class
+type Pool struct {
+	// MaxCredits is the burst, the pool starts full
+	MaxCredits float64
+	// RefillPerSecond is the number of credits added back every second
+	RefillPerSecond float64
+	// Cost is the number of credits of one request
+	Cost float64
+}
+
+type Config struct {
+	MatchingEngine    Pool
+	NonMatchingEngine Pool
+	// FailFast returns ErrRateLimited instead of waiting for credits
+	FailFast bool
+	// Backoff is how long a pool stays empty after Deribit answered 10028 too_many_requests
+	Backoff time.Duration
+	// Clock is the time of the refill, nil is the local time
+	Clock Clock
+}
+
+// Clock returns the current time, *deribit.Clock implements it
+type Clock interface {
+	Now() time.Time
+}
+
+type localClock struct{}
+
+func (localClock) Now() time.Time {
+	return time.Now()
+}
+
+// DefaultConfig matches the default Deribit limits: non-ME requests cost 500 of 50000 credits refilled
+// at 10000 per second (20 per second, burst 100), matching engine requests 5 per second with a burst of 20.
+func DefaultConfig() Config {
+	return Config{
+		MatchingEngine: Pool{
+			MaxCredits:      10000,
+			RefillPerSecond: 2500,
+			Cost:            500,
+		},
+		NonMatchingEngine: Pool{
+			MaxCredits:      50000,
+			RefillPerSecond: 10000,
+			Cost:            500,
+		},
+		Backoff: time.Second,
+	}
+}
+
+type bucket struct {
+	pool        Pool
+	credits     float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// ## refill adds the credits earned since the last update, never above MaxCredits
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.credits += now.Sub(b.last).Seconds() * b.pool.RefillPerSecond
+		if b.credits > b.pool.MaxCredits {
+			b.credits = b.pool.MaxCredits
+		}
+		b.last = now
+	}
+}
+
+// Limiter is a token bucket per class, it is safe for concurrent use
+type Limiter struct {
+	mu       sync.Mutex
+	buckets  [classCount]*bucket
+	failFast bool
+	backoff  time.Duration
+	clock    Clock
+}
+
+func New(cfg Config) *Limiter {
+	clock := cfg.Clock
+	if clock == nil {
+		clock = localClock{}
+	}
+	now := clock.Now()
+
+	l := &Limiter{
+		failFast: cfg.FailFast,
+		backoff:  cfg.Backoff,
+		clock:    clock,
+	}
+	l.buckets[MatchingEngine] = &bucket{pool: cfg.MatchingEngine, credits: cfg.MatchingEngine.MaxCredits, last: now}
+	l.buckets[NonMatchingEngine] = &bucket{pool: cfg.NonMatchingEngine, credits: cfg.NonMatchingEngine.MaxCredits, last: now}
+
+	return l
+}
+
+// ## Wait takes the credits of one request of method, waiting for the refill unless the limiter fails fast
+func (l *Limiter) Wait(ctx context.Context, method string) error {
+	class := Classify(method)
+
+	l.mu.Lock()
+	b := l.buckets[class]
+	if b.pool.RefillPerSecond <= 0 {
+		// ## Unlimited pool
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := l.clock.Now()
+	b.refill(now)
+
+	var wait time.Duration
+	if b.pausedUntil.After(now) {
+		wait = b.pausedUntil.Sub(now)
+	}
+	if b.credits < b.pool.Cost {
+		refillWait := time.Duration((b.pool.Cost - b.credits) / b.pool.RefillPerSecond * float64(time.Second))
+		wait = max(wait, refillWait)
+	}
+
+	if wait > 0 && l.failFast {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: %s %s, retry in %s", ErrRateLimited, class, method, wait)
+	}
+
+	// ## Reserve now, the credits go negative and later callers queue behind this one
+	b.credits -= b.pool.Cost
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		b.credits += b.pool.Cost
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// ## OnRateLimited empties the pool of method and pauses it for the backoff, call it on a 10028 response
+func (l *Limiter) OnRateLimited(method string) {
+	class := Classify(method)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.buckets[class]
+	now := l.clock.Now()
+	b.refill(now)
+
+	if b.credits > 0 {
+		b.credits = 0
+	}
+	if until := now.Add(l.backoff); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// ## Credits returns the credits left in the pool of class, negative while requests are queued
+func (l *Limiter) Credits(class Class) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.buckets[class]
+	b.refill(l.clock.Now())
+	return b.credits
+}
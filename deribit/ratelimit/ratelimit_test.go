@@ -0,0 +1,195 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
+)
+
+var _ Clock = (*deribit.Clock)(nil)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// ## Two requests of burst, one request refilled every 500ms, in both pools
+func testLimiter(clock Clock) *Limiter {
+	pool := Pool{MaxCredits: 1000, RefillPerSecond: 1000, Cost: 500}
+	return New(Config{
+		MatchingEngine:    pool,
+		NonMatchingEngine: pool,
+		FailFast:          true,
+		Backoff:           2 * time.Second,
+		Clock:             clock,
+	})
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		method string
+		want   Class
+	}{
+		{"private/buy", MatchingEngine},
+		{"/api/v2/private/sell", MatchingEngine},
+		{"/private/edit_by_label", MatchingEngine},
+		{"private/cancel_all_by_instrument", MatchingEngine},
+		{"private/get_positions", NonMatchingEngine},
+		{"public/get_order_book", NonMatchingEngine},
+		{"/api/v2/private/get_account_summary", NonMatchingEngine},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.method); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestWaitRefill(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	l := testLimiter(clock)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx, "private/buy"); err != nil {
+			t.Fatalf("request %d of the burst: %v", i+1, err)
+		}
+	}
+	if err := l.Wait(ctx, "private/buy"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("request after the burst = %v, want ErrRateLimited", err)
+	}
+	if got := l.Credits(MatchingEngine); got != 0 {
+		t.Errorf("credits = %v, want 0", got)
+	}
+
+	clock.advance(250 * time.Millisecond)
+	if got := l.Credits(MatchingEngine); got != 250 {
+		t.Errorf("credits after 250ms = %v, want 250", got)
+	}
+	if err := l.Wait(ctx, "private/buy"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("request with half the cost refilled = %v, want ErrRateLimited", err)
+	}
+
+	clock.advance(250 * time.Millisecond)
+	if err := l.Wait(ctx, "private/buy"); err != nil {
+		t.Fatalf("request after the refill: %v", err)
+	}
+
+	// ## The refill never goes above the burst
+	clock.advance(time.Minute)
+	if got := l.Credits(MatchingEngine); got != 1000 {
+		t.Errorf("credits after a minute = %v, want 1000", got)
+	}
+}
+
+func TestWaitClassesArePoolsOfTheirOwn(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	l := testLimiter(clock)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx, "private/buy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Wait(ctx, "private/cancel"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("matching engine request = %v, want ErrRateLimited", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx, "private/get_positions"); err != nil {
+			t.Fatalf("non matching engine request %d: %v", i+1, err)
+		}
+	}
+	if got := l.Credits(NonMatchingEngine); got != 0 {
+		t.Errorf("non matching engine credits = %v, want 0", got)
+	}
+}
+
+func TestOnRateLimited(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	l := testLimiter(clock)
+	ctx := context.Background()
+
+	l.OnRateLimited("private/buy")
+
+	if got := l.Credits(MatchingEngine); got != 0 {
+		t.Errorf("credits after 10028 = %v, want 0", got)
+	}
+	if got := l.Credits(NonMatchingEngine); got != 1000 {
+		t.Errorf("non matching engine credits = %v, want 1000", got)
+	}
+
+	// ## The pool refills during the backoff but stays paused
+	clock.advance(time.Second)
+	if got := l.Credits(MatchingEngine); got != 1000 {
+		t.Errorf("credits after 1s = %v, want 1000", got)
+	}
+	if err := l.Wait(ctx, "private/buy"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("request during the backoff = %v, want ErrRateLimited", err)
+	}
+	if err := l.Wait(ctx, "public/get_order_book"); err != nil {
+		t.Fatalf("non matching engine request during the backoff: %v", err)
+	}
+
+	clock.advance(time.Second)
+	if err := l.Wait(ctx, "private/buy"); err != nil {
+		t.Fatalf("request after the backoff: %v", err)
+	}
+
+	// ## A second 10028 never shortens a longer pause
+	l.OnRateLimited("private/buy")
+	clock.advance(time.Second)
+	l.OnRateLimited("private/sell")
+	clock.advance(1500 * time.Millisecond)
+	if err := l.Wait(ctx, "private/buy"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("request before the last backoff ended = %v, want ErrRateLimited", err)
+	}
+	clock.advance(500 * time.Millisecond)
+	if err := l.Wait(ctx, "private/buy"); err != nil {
+		t.Fatalf("request after the last backoff: %v", err)
+	}
+}
+
+func TestWaitCanceledGivesBackTheCredits(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	l := New(Config{
+		MatchingEngine:    Pool{MaxCredits: 500, RefillPerSecond: 1, Cost: 500},
+		NonMatchingEngine: Pool{MaxCredits: 500, RefillPerSecond: 1, Cost: 500},
+		Clock:             clock,
+	})
+
+	if err := l.Wait(context.Background(), "private/buy"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx, "private/buy"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait with a canceled ctx = %v, want context.Canceled", err)
+	}
+	if got := l.Credits(MatchingEngine); got != 0 {
+		t.Errorf("credits after a canceled wait = %v, want 0", got)
+	}
+}
+
+func TestWaitUnlimitedPool(t *testing.T) {
+	l := New(Config{FailFast: true, Clock: &fakeClock{}})
+
+	for i := 0; i < 100; i++ {
+		if err := l.Wait(context.Background(), "private/buy"); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package deribit
+
+type PortfolioItem struct {
+	AdditionalReserve        float64 `json:"additional_reserve"`
+	AvailableFunds           float64 `json:"available_funds"`
+	AvailableWithdrawalFunds float64 `json:"available_withdrawal_funds"`
+	Balance                  float64 `json:"balance"`
+	Currency                 string  `json:"currency"`
+	Equity                   float64 `json:"equity"`
+	InitialMargin            float64 `json:"initial_margin"`
+	MaintenanceMargin        float64 `json:"maintenance_margin"`
+	MarginBalance            float64 `json:"margin_balance"`
+	SpotReserve              float64 `json:"spot_reserve"`
+}
+
+type Portfolio struct {
+	BTC  PortfolioItem `json:"btc"`
+	ETH  PortfolioItem `json:"eth"`
+	USDC PortfolioItem `json:"usdc"`
+	USDT PortfolioItem `json:"usdt"`
+}
+
+type SubAccount struct {
+	Email                   string    `json:"email"`
+	ID                      int       `json:"id"`
+	IsPassword              bool      `json:"is_password"`
+	LoginEnabled            bool      `json:"login_enabled"`
+	MarginModel             string    `json:"margin_model"`
+	NotConfirmedEmail       string    `json:"not_confirmed_email"`
+	Portfolio               Portfolio `json:"portfolio"`
+	ProofID                 string    `json:"proof_id"`
+	ProofIDSignature        string    `json:"proof_id_signature"`
+	ReceiveNotifications    bool      `json:"receive_notifications"`
+	SecurityKeysAssignments []string  `json:"security_keys_assignments"`
+	SecurityKeysEnabled     bool      `json:"security_keys_enabled"`
+	SystemName              string    `json:"system_name"`
+	Type                    string    `json:"type"`
+	Username                string    `json:"username"`
+}
@@ -0,0 +1,15 @@
+// Package deribit holds what the REST (api) and WebSocket (ws) clients share: the request and result types
+// of orders, positions, account summaries, subaccounts and authentication, the Error model, the signature Clock
+// and the Transport that sends a JSON-RPC method.
+package deribit
+
+import (
+	"context"
+)
+
+// Transport sends a JSON-RPC method (e.g. private/buy) with params and decodes the result field of the
+// response into result, result may be nil. A Deribit error is returned as the error of the call.
+// api.Client implements it over HTTP and ws.DeribitClient over a WebSocket connection.
+type Transport interface {
+	Call(ctx context.Context, method string, params any, result any) error
+}
@@ -1,46 +1,31 @@
 package ws
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"log"
+	"time"
 
-	"github.com/gorilla/websocket"
+	"bitbucket.org/ohm89/go-deribit/deribit"
 )
 
-type AuthResult struct {
-	AccessToken        string   `json:"access_token"`
-	EnabledFeatures    []string `json:"enabled_features"`
-	ExpiresIn          int      `json:"expires_in"`
-	GoogleLogin        bool     `json:"google_login"`
-	MandatoryTFAStatus string   `json:"mandatory_tfa_status"`
-	RefreshToken       string   `json:"refresh_token"`
-	Scope              string   `json:"scope"`
-	SID                string   `json:"sid,omitempty"`
-	State              string   `json:"state,omitempty"`
-	TokenType          string   `json:"token_type"`
-}
+// ## Requests and results of authentication are the deribit types shared with the api client
+type (
+	AuthRequest = deribit.AuthRequest
+	AuthResult  = deribit.AuthResult
+)
 
 type AuthResponse struct {
 	ID      int        `json:"id"`
 	JSONRPC string     `json:"jsonrpc"`
 	Result  AuthResult `json:"result"`
+	Error   *Error     `json:"error,omitempty"`
 }
 
-type AuthRequest struct {
-	GrantType    string `json:"grant_type"`
-	ClientID     string `json:"client_id"`
-	ClientSecret string `json:"client_secret"`
-	RefreshToken string `json:"refresh_token,omitempty"`
-	Timestamp    int64  `json:"timestamp,omitempty"`
-	Signature    string `json:"signature,omitempty"`
-	Nonce        string `json:"nonce,omitempty"`
-	Data         string `json:"data,omitempty"`
-	State        string `json:"state,omitempty"`
-	Scope        string `json:"scope,omitempty"`
-}
-
-func Authenticate(c *DeribitClient) (*AuthResponse, error) {
+// ## Authenticate opens a session with client credentials, or with a signature when WithSignatureAuth is set
+func Authenticate(ctx context.Context, c *DeribitClient) (*AuthResponse, error) {
+	if c.signatureAuth {
+		return AuthenticateWithSignature(ctx, c)
+	}
 
 	authRequest := &AuthRequest{
 		GrantType:    "client_credentials",
@@ -48,103 +33,93 @@ func Authenticate(c *DeribitClient) (*AuthResponse, error) {
 		ClientSecret: c.clientSecret,
 	}
 
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "public/auth",
-		"params":  authRequest,
-	}
-
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal authentication message: %w", err)
-	}
-
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send authentication message: %w", err)
-	}
-
-	_, message, err := c.conn.ReadMessage()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read authentication response: %w", err)
-	}
+	return sendAuth(ctx, c, authRequest)
+}
 
-	log.Printf("Received authentication response: %s \n\n", message)
+// AuthenticateWithSignature opens a session with grant_type=client_signature, the client secret is not sent
+func AuthenticateWithSignature(ctx context.Context, c *DeribitClient) (*AuthResponse, error) {
+	timestamp := c.clock.Now().UnixMilli()
+	nonce := deribit.NewNonce()
 
-	// Parse the authentication response and save the access_token
-	var authResponse AuthResponse
-	err = json.Unmarshal(message, &authResponse)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal authentication response: %w", err)
+	authRequest := &AuthRequest{
+		GrantType: "client_signature",
+		ClientID:  c.clientID,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: deribit.Signature(c.clientSecret, timestamp, nonce, ""),
 	}
 
-	result := authResponse.Result
+	return sendAuth(ctx, c, authRequest)
+}
 
-	accessToken := result.AccessToken
-	refreshToken := result.RefreshToken
+// SyncClock sets the clock offset used by signatures from public/get_time and returns it
+func (c *DeribitClient) SyncClock(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
 
-	c.accessToken = accessToken
-	c.refreshToken = refreshToken
+	sent := time.Now()
 
-	c.isPrivate = true
+	var resp struct {
+		Result int64 `json:"result"`
+	}
+	if err := c.call(ctx, "public/get_time", map[string]interface{}{}, &resp); err != nil {
+		return 0, fmt.Errorf("failed to sync clock: %w", err)
+	}
 
-	// ## [DEBUG]
-	// fmt.Printf("\n\n Access Token: %s \n\n", c.accessToken)
-	// fmt.Printf("\n\n Refresh Token: %s \n\n", c.refreshToken)
+	offset := c.clock.Sync(resp.Result, sent, time.Now())
+	c.logger.Debug("clock synced", "offset", offset)
 
-	return &authResponse, nil
+	return offset, nil
 }
 
-func RefreshAuth(c *DeribitClient) (*AuthResponse, error) {
+func RefreshAuth(ctx context.Context, c *DeribitClient) (*AuthResponse, error) {
+
+	refreshToken, _, _ := c.session()
 
 	authRequest := &AuthRequest{
 		GrantType:    "refresh_token",
-		RefreshToken: c.refreshToken,
-	}
-
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "public/auth",
-		"params":  authRequest,
-	}
-
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal authentication message: %w", err)
+		RefreshToken: refreshToken,
 	}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send authentication message: %w", err)
-	}
+	return sendAuth(ctx, c, authRequest)
+}
 
-	_, message, err := c.conn.ReadMessage()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read authentication response: %w", err)
+func sendAuth(ctx context.Context, c *DeribitClient, authRequest *AuthRequest) (*AuthResponse, error) {
+	var scope func(s *sessionScope)
+	if authRequest.GrantType != "refresh_token" {
+		// ## Credentials open a session of the main account, a refresh keeps the subaccount or session name
+		scope = func(s *sessionScope) {
+			*s = sessionScope{}
+		}
 	}
+	return sendToken(ctx, c, "public/auth", authRequest, authRequest.GrantType, scope)
+}
 
-	log.Printf("Received authentication response: %s \n\n", message)
+// ## sendToken sends a request that answers with tokens (auth, exchange_token, fork_token) and makes them the session of the connection,
+// scope updates the subaccount or session name of the new session, nil keeps them
+func sendToken(ctx context.Context, c *DeribitClient, method string, params interface{}, name string, scope func(s *sessionScope)) (*AuthResponse, error) {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
 
 	// Parse the authentication response and save the access_token
 	var authResponse AuthResponse
-	err = json.Unmarshal(message, &authResponse)
+	err := c.call(ctx, method, params, &authResponse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal authentication response: %w", err)
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
 	}
 
-	result := authResponse.Result
-
-	accessToken := result.AccessToken
-	refreshToken := result.RefreshToken
+	c.logger.Debug("authenticated", "grant_type", name, "result", authResponse.Result)
 
-	c.accessToken = accessToken
-	c.refreshToken = refreshToken
+	result := authResponse.Result
 
+	c.mu.Lock()
+	c.accessToken = result.AccessToken
+	c.refreshToken = result.RefreshToken
 	c.isPrivate = true
-
-	// ## [DEBUG]
-	// fmt.Printf("\n\n Access Token: %s \n\n", c.accessToken)
-	// fmt.Printf("\n\n Refresh Token: %s \n\n", c.refreshToken)
+	if scope != nil {
+		scope(&c.scope)
+	}
+	c.mu.Unlock()
 
 	return &authResponse, nil
 }
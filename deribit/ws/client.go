@@ -3,320 +3,641 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"bitbucket.org/ohm89/go-deribit/deribit"
+	"bitbucket.org/ohm89/go-deribit/deribit/ratelimit"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	defaultCallTimeout     = 30 * time.Second
+	notificationBufferSize = 1024
+
+	defaultReconnectInitialDelay = 500 * time.Millisecond
+	defaultReconnectMaxDelay     = 30 * time.Second
+
+	sendQueueSize = 256
+	writeWait     = 10 * time.Second
+)
+
+// ## Outbound lanes, the writer always drains a higher lane first
+const (
+	laneCancel = iota // ## cancels and heartbeat answers
+	laneOrder         // ## buy, sell, edit and close position
+	laneQuery         // ## everything else
+	laneCount
+)
+
+var (
+	errClientClosed = errors.New("websocket client is closed")
+
+	// ErrSendQueueFull is returned when the outbound lane of the request stays full until the context is done
+	ErrSendQueueFull = errors.New("websocket send queue is full")
+)
+
+// ReconnectPolicy is the backoff between reconnect attempts,
+// the delay doubles from InitialDelay up to MaxDelay with random jitter.
+type ReconnectPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// MaxAttempts is the number of attempts before giving up, 0 retries forever
+	MaxAttempts int
+}
+
 type DeribitClient struct {
 	websocketUrl string
 	conn         *websocket.Conn
-	channels     []string
 	mu           sync.Mutex
 	clientID     string
 	clientSecret string
 	accessToken  string
 	refreshToken string
 	isPrivate    bool
+	scope        sessionScope
+
+	// ## signatureAuth authenticates with grant_type=client_signature instead of client credentials
+	signatureAuth bool
+	clock         deribit.Clock
+
+	// ## channels restored after reconnect through public/subscribe and private/subscribe
+	publicChannels  map[string]struct{}
+	privateChannels map[string]struct{}
+
+	// ## session settings restored after reconnect
+	heartBeatInterval     int
+	softwareClientName    string
+	softwareClientVersion string
+
+	reconnectPolicy ReconnectPolicy
+	onDisconnect    func(err error)
+	onReconnect     func()
+	onResubscribed  func(channels []string)
+
+	superviseOnce sync.Once
+	closeOnce     sync.Once
+	closed        chan struct{}
+	closeErr      error
+
+	callTimeout time.Duration
+	requestID   uint64
+
+	// ## pending calls waiting for the response with the same id
+	pendingMu sync.Mutex
+	pending   map[uint64]chan []byte
+	done      chan struct{}
+	readErr   error
+
+	// ## outbound queue of the current connection, only its writer goroutine writes to conn
+	lanes [laneCount]chan []byte
+
+	notifications chan *WebSocketResponse
+
+	// ## typed subscription decoders by channel name
+	handlersMu sync.RWMutex
+	handlers   map[string]func(data json.RawMessage)
+
+	logger  *slog.Logger
+	limiter *ratelimit.Limiter
 }
 
-type heartBeatResponse struct {
-	params  string          `json:"type"`
-	Channel string          `json:"channel,omitempty"`
-	Market  string          `json:"market,omitempty"`
-	Data    json.RawMessage `json:"data,omitempty"`
-	Code    int             `json:"code,omitempty"`
-	Msg     string          `json:"msg,omitempty"`
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type heartBeatParams struct {
+	Type string `json:"type"`
 }
 
 // NewDeribitClient is an exported function that creates a new Deribit WebSocket client
-func NewDeribitClient(clientID, clientSecret string) *DeribitClient {
-	return &DeribitClient{
-		clientID:     clientID,
-		clientSecret: clientSecret,
+func NewDeribitClient(clientID, clientSecret string, opts ...Option) *DeribitClient {
+	c := &DeribitClient{
+		clientID:      clientID,
+		clientSecret:  clientSecret,
+		callTimeout:   defaultCallTimeout,
+		pending:       make(map[uint64]chan []byte),
+		notifications: make(chan *WebSocketResponse, notificationBufferSize),
+
+		publicChannels:  make(map[string]struct{}),
+		privateChannels: make(map[string]struct{}),
+		reconnectPolicy: ReconnectPolicy{
+			InitialDelay: defaultReconnectInitialDelay,
+			MaxDelay:     defaultReconnectMaxDelay,
+		},
+		closed:   make(chan struct{}),
+		handlers: make(map[string]func(data json.RawMessage)),
+		logger:   slog.Default(),
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
-func (c *DeribitClient) Connect(websocketUrl string) error {
+// ## Connect dials the WebSocket and starts the supervisor that reconnects when the connection drops
+func (c *DeribitClient) Connect(ctx context.Context, websocketUrl string) error {
+	c.logger.Debug("websocket connect", "url", websocketUrl)
 
-	// ## DEBUG
-	fmt.Printf("Web Socket URL: %s \n\n", websocketUrl)
+	c.websocketUrl = websocketUrl
 
+	if err := c.dial(ctx); err != nil {
+		return err
+	}
+
+	c.superviseOnce.Do(func() {
+		go c.supervise()
+	})
+
+	return nil
+}
+
+func (c *DeribitClient) dial(ctx context.Context) error {
 	// WebSocket connection URL
-	u := url.URL{Scheme: "wss", Host: websocketUrl, Path: "/ws/api/v2"}
+	u := url.URL{Scheme: "wss", Host: c.websocketUrl, Path: "/ws/api/v2"}
 
 	// Connect to the WebSocket
-	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 
+	done := make(chan struct{})
+
+	// ## New lanes per connection, requests queued for a dropped connection are never sent on the next one
+	var lanes [laneCount]chan []byte
+	for i := range lanes {
+		lanes[i] = make(chan []byte, sendQueueSize)
+	}
+
+	c.pendingMu.Lock()
+	// ## Close ran while dialing, it only closes the conn it finds so this one must not be kept
+	select {
+	case <-c.closed:
+		c.pendingMu.Unlock()
+		conn.Close()
+		return errClientClosed
+	default:
+	}
 	c.conn = conn
-	c.websocketUrl = websocketUrl
+	c.done = done
+	c.readErr = nil
+	c.lanes = lanes
+	c.pendingMu.Unlock()
+
+	go c.readLoop(conn, done)
+	go c.writeLoop(conn, done, lanes)
 
 	return nil
 }
 
+// ## GetConn returns the current connection, do not write to it directly, use Call so the writer goroutine sends it
 func (c *DeribitClient) GetConn() *websocket.Conn {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
 	return c.conn
 }
 
-func (c *DeribitClient) Subscribe(channels ...string) error {
+// ## closeConn closes the current connection, dial swaps it under pendingMu
+func (c *DeribitClient) closeConn() {
+	if conn := c.GetConn(); conn != nil {
+		conn.Close()
+	}
+}
+
+// ## Set how long helper functions wait for the matching response
+func (c *DeribitClient) SetCallTimeout(timeout time.Duration) {
+	c.callTimeout = timeout
+}
+
+// ## Set the backoff used by the supervisor between reconnect attempts
+func (c *DeribitClient) SetReconnectPolicy(policy ReconnectPolicy) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.channels = append(c.channels, channels...)
+	c.reconnectPolicy = policy
+}
 
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "public/subscribe",
-		"params": map[string]interface{}{
-			"channels": c.channels,
-		},
-	}
+// ## OnDisconnect is called with the read error when the connection drops
+func (c *DeribitClient) OnDisconnect(fn func(err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		// log.Fatalf("failed to marshal subscription message: %v", err)
-		return err
-	}
+	c.onDisconnect = fn
+}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		// log.Fatalf("failed to send subscription message: %v", err)
-		return err
-	}
+// ## OnReconnect is called when a new connection is up and authenticated again, before resubscription
+func (c *DeribitClient) OnReconnect(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return nil
+	c.onReconnect = fn
 }
 
-func (c *DeribitClient) PrivateSubscribe(channels ...string) error {
+// ## OnResubscribed is called with every restored channel, strategies can resync their state here
+func (c *DeribitClient) OnResubscribed(fn func(channels []string)) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.channels = append(c.channels, channels...)
+	c.onResubscribed = fn
+}
 
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "private/subscribe",
-		"params": map[string]interface{}{
-			"channels": c.channels,
-		},
+// ## callContext applies the call timeout when ctx has no deadline of its own
+func (c *DeribitClient) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, c.callTimeout)
+}
+
+// ## Lane of a method, cancels go ahead of orders and orders ahead of queries
+func laneOf(method string) int {
+	switch {
+	case strings.HasPrefix(method, "private/cancel"), method == "public/test":
+		return laneCancel
+	case method == "private/buy", method == "private/sell", strings.HasPrefix(method, "private/edit"),
+		method == "private/close_position":
+		return laneOrder
+	default:
+		return laneQuery
+	}
+}
+
+// ## connection returns the done channel and lanes of the current connection, both from one reconnect
+func (c *DeribitClient) connection() (chan struct{}, [laneCount]chan []byte) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	return c.done, c.lanes
+}
+
+// ## Queue one JSON-RPC message for the writer goroutine of the connection of done and lanes,
+// waits for space until ctx is done
+func (c *DeribitClient) send(ctx context.Context, done chan struct{}, lanes [laneCount]chan []byte, msg *rpcRequest) error {
+	if done == nil {
+		return errors.New("websocket is not connected")
+	}
+	lane := lanes[laneOf(msg.Method)]
 
 	jsonMsg, err := json.Marshal(msg)
 	if err != nil {
-		// log.Fatalf("failed to marshal subscription message: %v", err)
-		return err
+		return fmt.Errorf("failed to marshal %s message: %w", msg.Method, err)
 	}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		// log.Fatalf("failed to send subscription message: %v", err)
-		return err
+	select {
+	case lane <- jsonMsg:
+		return nil
+	default:
 	}
 
-	return nil
+	select {
+	case lane <- jsonMsg:
+		return nil
+	case <-done:
+		return fmt.Errorf("connection closed: %w", c.connErr())
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrSendQueueFull, ctx.Err())
+	}
 }
 
-func (c *DeribitClient) Unsubscribe(channels ...string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// ## Only writer of one connection, gorilla/websocket does not allow concurrent writers
+func (c *DeribitClient) writeLoop(conn *websocket.Conn, done chan struct{}, lanes [laneCount]chan []byte) {
+	for {
+		var message []byte
 
-	// Remove the channels from the list of subscribed channels
-	newChannels := make([]string, 0, len(c.channels))
-	for _, ch := range c.channels {
-		found := false
-		for _, unsubCh := range channels {
-			if ch == unsubCh {
-				found = true
-				break
+		select {
+		case message = <-lanes[laneCancel]:
+		default:
+			select {
+			case message = <-lanes[laneCancel]:
+			case message = <-lanes[laneOrder]:
+			default:
+				select {
+				case message = <-lanes[laneCancel]:
+				case message = <-lanes[laneOrder]:
+				case message = <-lanes[laneQuery]:
+				case <-done:
+					return
+				}
 			}
 		}
-		if !found {
-			newChannels = append(newChannels, ch)
+
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			c.logger.Error("failed to write websocket message", "error", err)
+
+			// ## The read loop fails on the closed connection and the supervisor reconnects
+			conn.Close()
+			return
 		}
 	}
-	c.channels = newChannels
+}
 
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "public/unsubscribe",
-		"params": map[string]interface{}{
-			"channels": channels,
-		},
-	}
+// Call sends a JSON-RPC request with a unique id, waits for the matching response and decodes its result
+// into result, a Deribit error is returned as *Error. DeribitClient is a deribit.Transport.
+// Without a deadline in ctx the call times out after WithCallTimeout.
+func (c *DeribitClient) Call(ctx context.Context, method string, params any, result any) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
 
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return err
+	var resp struct {
+		Result json.RawMessage `json:"result"`
 	}
-
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
+	if err := c.call(ctx, method, params, &resp); err != nil {
 		return err
 	}
 
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result: %w", method, err)
+	}
 	return nil
 }
 
-func (c *DeribitClient) PrivateUnsubscribe(channels ...string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// ## call is Call decoding the whole response into out
+func (c *DeribitClient) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
 
-	// Remove the channels from the list of subscribed channels
-	newChannels := make([]string, 0, len(c.channels))
-	for _, ch := range c.channels {
-		found := false
-		for _, unsubCh := range channels {
-			if ch == unsubCh {
-				found = true
-				break
-			}
-		}
-		if !found {
-			newChannels = append(newChannels, ch)
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx, method); err != nil {
+			return fmt.Errorf("%s: %w", method, err)
 		}
 	}
-	c.channels = newChannels
 
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "private/unsubscribe",
-		"params": map[string]interface{}{
-			"channels": channels,
-		},
+	id := atomic.AddUint64(&c.requestID, 1)
+	ch := make(chan []byte, 1)
+
+	// ## The response and a close are waited for on the connection the request is queued on
+	c.pendingMu.Lock()
+	done, lanes := c.done, c.lanes
+	c.pending[id] = ch
+	c.pendingMu.Unlock()
+
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pending, id)
+		c.pendingMu.Unlock()
+	}()
+
+	if done == nil {
+		return errors.New("websocket is not connected")
 	}
 
-	jsonMsg, err := json.Marshal(msg)
+	err := c.send(ctx, done, lanes, &rpcRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to send %s request: %w", method, err)
 	}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		return err
+	select {
+	case message := <-ch:
+		var envelope struct {
+			Error *Error `json:"error,omitempty"`
+		}
+		if err := json.Unmarshal(message, &envelope); err != nil {
+			return fmt.Errorf("failed to unmarshal %s response: %w", method, err)
+		}
+		if envelope.Error != nil {
+			if envelope.Error.Code == deribit.CodeTooManyRequests && c.limiter != nil {
+				c.limiter.OnRateLimited(method)
+			}
+			return envelope.Error
+		}
+
+		if out != nil {
+			if err := json.Unmarshal(message, out); err != nil {
+				return fmt.Errorf("failed to unmarshal %s response: %w", method, err)
+			}
+		}
+		return nil
+	case <-done:
+		return fmt.Errorf("connection closed while waiting for %s response: %w", method, c.connErr())
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
 	}
+}
 
-	return nil
+func (c *DeribitClient) connErr() error {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	return c.readErr
 }
 
-func (c *DeribitClient) UnsubscribeAll() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// ## Read every message of one connection and route it to the pending call or the notifications
+func (c *DeribitClient) readLoop(conn *websocket.Conn, done chan struct{}) {
+	defer close(done)
 
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "public/unsubscribe_all",
-		"params":  map[string]interface{}{},
-	}
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			c.pendingMu.Lock()
+			c.readErr = err
+			c.pendingMu.Unlock()
+			return
+		}
 
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return err
+		switch messageType {
+		case websocket.TextMessage:
+			c.handleTextMessage(message)
+		case websocket.BinaryMessage:
+			c.logger.Debug("unexpected binary websocket message", "size", len(message))
+		default:
+			c.logger.Warn("unexpected websocket message type", "type", messageType)
+		}
 	}
+}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		return err
-	}
+func (c *DeribitClient) Subscribe(ctx context.Context, channels ...string) error {
+	return c.subscribe(ctx, "public/subscribe", c.publicChannels, channels)
+}
 
-	return nil
+func (c *DeribitClient) PrivateSubscribe(ctx context.Context, channels ...string) error {
+	return c.subscribe(ctx, "private/subscribe", c.privateChannels, channels)
 }
 
-func (c *DeribitClient) PrivateUnsubscribeAll() error {
+func (c *DeribitClient) Unsubscribe(ctx context.Context, channels ...string) error {
+	return c.unsubscribe(ctx, "public/unsubscribe", c.publicChannels, channels)
+}
+
+func (c *DeribitClient) PrivateUnsubscribe(ctx context.Context, channels ...string) error {
+	return c.unsubscribe(ctx, "private/unsubscribe", c.privateChannels, channels)
+}
+
+func (c *DeribitClient) UnsubscribeAll(ctx context.Context) error {
+	return c.unsubscribeAll(ctx, "public/unsubscribe_all", c.publicChannels)
+}
+
+func (c *DeribitClient) PrivateUnsubscribeAll(ctx context.Context) error {
+	return c.unsubscribeAll(ctx, "private/unsubscribe_all", c.privateChannels)
+}
+
+// ## Channels returns the public and private channels that are restored after reconnect
+func (c *DeribitClient) Channels() (public []string, private []string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "private/unsubscribe_all",
-		"params":  map[string]interface{}{},
-	}
+	return channelList(c.publicChannels), channelList(c.privateChannels)
+}
 
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return err
+// ## The channel sets are updated under c.mu and the call is made without it, a round trip must not
+// block the supervisor and the other users of c.mu. A channel is added before the call so a reconnect
+// meanwhile restores it, and removed again when the call fails.
+func (c *DeribitClient) subscribe(ctx context.Context, method string, set map[string]struct{}, channels []string) error {
+	c.mu.Lock()
+	added := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		if _, ok := set[ch]; !ok {
+			set[ch] = struct{}{}
+			added = append(added, ch)
+		}
 	}
+	c.mu.Unlock()
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
+	err := c.call(ctx, method, map[string]interface{}{
+		"channels": channels,
+	}, nil)
 	if err != nil {
+		c.mu.Lock()
+		for _, ch := range added {
+			delete(set, ch)
+		}
+		c.mu.Unlock()
 		return err
 	}
 
 	return nil
 }
 
-func (c *DeribitClient) Ping() error {
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "public/test",
-		"params":  map[string]interface{}{},
+func (c *DeribitClient) unsubscribe(ctx context.Context, method string, set map[string]struct{}, channels []string) error {
+	c.mu.Lock()
+	// Remove the channels from the set of subscribed channels
+	for _, ch := range channels {
+		delete(set, ch)
 	}
+	c.mu.Unlock()
+	c.removeHandlers(channels...)
 
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		log.Fatalf("failed to marshal test(ping) message: %v", err)
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	return c.call(ctx, method, map[string]interface{}{
+		"channels": channels,
+	}, nil)
+}
+
+func (c *DeribitClient) unsubscribeAll(ctx context.Context, method string, set map[string]struct{}) error {
+	c.mu.Lock()
+	channels := channelList(set)
+	clear(set)
+	c.mu.Unlock()
+	c.removeHandlers(channels...)
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	return c.call(ctx, method, nil, nil)
+}
+
+func channelList(set map[string]struct{}) []string {
+	channels := make([]string, 0, len(set))
+	for ch := range set {
+		channels = append(channels, ch)
 	}
+	return channels
+}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
+// ## Ping does not wait for the answer, it is also used to answer heartbeat from the read loop.
+// It is sent without an id so the answer is not delivered as a notification.
+func (c *DeribitClient) Ping() error {
+	// ## Never block the read loop for long when the queue is full
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	done, lanes := c.connection()
+	err := c.send(ctx, done, lanes, &rpcRequest{
+		JSONRPC: "2.0",
+		Method:  "public/test",
+		Params:  map[string]interface{}{},
+	})
 	if err != nil {
-		log.Fatalf("failed to send test(ping) message: %v", err)
+		return fmt.Errorf("failed to send test(ping) message: %w", err)
 	}
 
-	return err
+	return nil
 }
 
 func (c *DeribitClient) PingRegular(ctx context.Context, duration time.Duration) {
 	go func() {
 		t := time.NewTicker(duration)
+		defer t.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-t.C:
+				// ## Keep ticking while the supervisor reconnects
 				if err := c.Ping(); err != nil {
-					return
+					c.logger.Warn("failed to ping", "error", err)
 				}
 			}
 		}
 	}()
 }
 
-func (c *DeribitClient) SetHeartBeat(interval int) error {
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "public/set_heartbeat",
-		"params": map[string]interface{}{
-			"interval": interval, // ## In second
-		},
-	}
+func (c *DeribitClient) SetHeartBeat(ctx context.Context, interval int) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
 
-	jsonMsg, err := json.Marshal(msg)
+	err := c.call(ctx, "public/set_heartbeat", map[string]interface{}{
+		"interval": interval, // ## In second
+	}, nil)
 	if err != nil {
-		log.Fatalf("failed to marshal SetHeartBeat message: %v", err)
+		return fmt.Errorf("failed to send SetHeartBeat message: %w", err)
 	}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		log.Fatalf("failed to send SetHeartBeat message: %v", err)
-	}
+	c.mu.Lock()
+	c.heartBeatInterval = interval
+	c.mu.Unlock()
 
-	return err
+	return nil
 }
 
-func (c *DeribitClient) handleHeartbeat() error {
-	log.Println("Received heartbeat message")
+func (c *DeribitClient) handleHeartbeat(params json.RawMessage) error {
+	c.logger.Debug("received heartbeat")
+
+	var heartBeat heartBeatParams
+	if err := json.Unmarshal(params, &heartBeat); err != nil {
+		return err
+	}
+
+	// ## Only test_request expects an answer from the client
+	if heartBeat.Type != "test_request" {
+		return nil
+	}
 
 	// Prepare the heartbeat response
 	err := c.Ping()
@@ -325,102 +646,299 @@ func (c *DeribitClient) handleHeartbeat() error {
 }
 
 // ## Hello to set program for deribit to known software
-func (c *DeribitClient) Hello(softwareClientName string, softwareClientVersion string) error {
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "public/hello",
-		"params": map[string]interface{}{
-			"client_name":    softwareClientName,
-			"client_version": softwareClientVersion,
-		},
-	}
-
-	jsonMsg, err := json.Marshal(msg)
+func (c *DeribitClient) Hello(ctx context.Context, softwareClientName string, softwareClientVersion string) error {
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	err := c.call(ctx, "public/hello", map[string]interface{}{
+		"client_name":    softwareClientName,
+		"client_version": softwareClientVersion,
+	}, nil)
 	if err != nil {
-		log.Fatalf("failed to marshal Hello message: %v", err)
+		return fmt.Errorf("failed to send Hello message: %w", err)
 	}
 
-	err = c.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		log.Fatalf("failed to send Hello message: %v", err)
-	}
+	c.mu.Lock()
+	c.softwareClientName = softwareClientName
+	c.softwareClientVersion = softwareClientVersion
+	c.mu.Unlock()
 
-	return err
+	return nil
 }
 
-// ## Main Run For Testing client
+// ## Close stops the supervisor and closes the connection, Receive returns an error after it
 func (c *DeribitClient) Close() {
-	c.conn.Close()
+	c.shutdown(errClientClosed)
+}
+
+func (c *DeribitClient) shutdown(err error) {
+	c.closeOnce.Do(func() {
+		c.pendingMu.Lock()
+		c.closeErr = err
+		c.pendingMu.Unlock()
+
+		close(c.closed)
+	})
+
+	c.closeConn()
 }
 
 func (c *DeribitClient) handleTextMessage(message []byte) {
-	var msg map[string]interface{}
+	var msg WebSocketResponse
 	err := json.Unmarshal(message, &msg)
 	if err != nil {
-		log.Printf("failed to unmarshal message: %v", err)
+		c.logger.Error("failed to unmarshal websocket message", "error", err)
+		return
+	}
+
+	// ## Response of a pending call
+	if msg.Method == "" && msg.ID != 0 {
+		c.pendingMu.Lock()
+		ch, ok := c.pending[msg.ID]
+		c.pendingMu.Unlock()
+
+		if ok {
+			ch <- message
+			return
+		}
+	}
+
+	// ## A response without id answers Ping, it is never a notification
+	if msg.Method == "" && msg.ID == 0 {
 		return
 	}
 
-	method, _ := msg["method"].(string)
-	switch method {
-	case "heartbeat":
-		c.handleHeartbeat()
+	if msg.Method == "heartbeat" {
+		if err := c.handleHeartbeat(msg.Params); err != nil {
+			c.logger.Warn("failed to answer heartbeat", "error", err)
+		}
+		return
+	}
+
+	if msg.Method == "subscription" && c.dispatchSubscription(msg.Params) {
+		return
+	}
+
+	select {
+	case c.notifications <- &msg:
 	default:
-		fmt.Printf("Received message [Private: %t]: %s\n\n", c.isPrivate, message)
+		c.logger.Warn("notification buffer is full, dropping message", "method", msg.Method)
 	}
 }
 
-// ## Reconnect
+// ## Supervise the connection and reconnect every time it drops until Close is called
+func (c *DeribitClient) supervise() {
+	for {
+		c.pendingMu.Lock()
+		done := c.done
+		c.pendingMu.Unlock()
+
+		select {
+		case <-done:
+		case <-c.closed:
+			// ## A reconnect may have dialed after Close, do not leak it
+			c.closeConn()
+			return
+		}
+
+		select {
+		case <-c.closed:
+			return
+		default:
+		}
+
+		err := c.connErr()
+		c.logger.Warn("websocket disconnected", "error", err)
+
+		c.mu.Lock()
+		onDisconnect := c.onDisconnect
+		c.mu.Unlock()
+
+		if onDisconnect != nil {
+			onDisconnect(err)
+		}
+
+		if err := c.reconnect(); err != nil {
+			c.logger.Error("failed to reconnect", "error", err)
+			c.shutdown(err)
+			return
+		}
+	}
+}
+
+// ## Reconnect with exponential backoff and jitter
 func (c *DeribitClient) reconnect() error {
+	c.mu.Lock()
+	policy := c.reconnectPolicy
+	c.mu.Unlock()
+
+	delay := policy.InitialDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(jitter(delay)):
+		case <-c.closed:
+			return errClientClosed
+		}
+
+		err := c.restore()
+		if err == nil {
+			return nil
+		}
+
+		c.logger.Warn("reconnect attempt failed", "attempt", attempt, "error", err)
+
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return fmt.Errorf("giving up after %d reconnect attempts: %w", attempt, err)
+		}
+
+		delay *= 2
+		if delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+// ## Random delay between half and the full backoff delay
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// ## Open a new connection and restore hello, heartbeat, authentication and subscriptions
+func (c *DeribitClient) restore() error {
 	// Close the existing connection
-	c.conn.Close()
+	c.closeConn()
+
+	// ## Restore is not bound to a caller, every call still has the call timeout
+	ctx := context.Background()
 
 	// Reconnect to the WebSocket
-	err := c.Connect(c.websocketUrl)
+	if err := c.dial(ctx); err != nil {
+		return err
+	}
+
+	err := c.restoreSession(ctx)
 	if err != nil {
+		c.closeConn()
 		return err
 	}
 
-	// Resubscribe to the channels
-	if c.isPrivate {
-		err = c.PrivateSubscribe(c.channels...)
-	} else {
-		err = c.Subscribe(c.channels...)
+	return nil
+}
+
+func (c *DeribitClient) restoreSession(ctx context.Context) error {
+	c.mu.Lock()
+	name, version := c.softwareClientName, c.softwareClientVersion
+	interval := c.heartBeatInterval
+	onReconnect := c.onReconnect
+	onResubscribed := c.onResubscribed
+	c.mu.Unlock()
+
+	if name != "" {
+		if err := c.Hello(ctx, name, version); err != nil {
+			return err
+		}
 	}
+
+	if interval > 0 {
+		if err := c.SetHeartBeat(ctx, interval); err != nil {
+			return err
+		}
+	}
+
+	if _, _, isPrivate := c.session(); isPrivate {
+		if err := c.reauthenticate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if onReconnect != nil {
+		onReconnect()
+	}
+
+	channels, err := c.resubscribe(ctx)
 	if err != nil {
 		return err
 	}
 
+	if onResubscribed != nil {
+		onResubscribed(channels)
+	}
+
 	return nil
 }
 
+// ## Use the stored refresh token first and fall back to client credentials,
+// then exchange and fork again so the connection stays on the subaccount or named session
+func (c *DeribitClient) reauthenticate(ctx context.Context) error {
+	refreshToken, scope, _ := c.session()
+	if refreshToken != "" {
+		_, err := RefreshAuth(ctx, c)
+		if err == nil {
+			return nil
+		}
+		c.logger.Warn("failed to refresh authentication, using client credentials", "error", err)
+	}
+
+	if _, err := Authenticate(ctx, c); err != nil {
+		return err
+	}
+
+	if err := c.restoreScope(ctx, scope); err != nil {
+		// ## The connection is on the main account now, the next attempt must not refresh that session
+		c.mu.Lock()
+		c.refreshToken = ""
+		c.scope = scope
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Resubscribe to the public and private channels
+func (c *DeribitClient) resubscribe(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	public := channelList(c.publicChannels)
+	private := channelList(c.privateChannels)
+	c.mu.Unlock()
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	if len(public) > 0 {
+		err := c.call(ctx, "public/subscribe", map[string]interface{}{
+			"channels": public,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(private) > 0 {
+		err := c.call(ctx, "private/subscribe", map[string]interface{}{
+			"channels": private,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return append(public, private...), nil
+}
+
 // ## Main Run Client in loop
 func (c *DeribitClient) Run() {
 	for {
-		messageType, message, err := c.conn.ReadMessage()
+		msg, err := c.Receive()
 		if err != nil {
-			// Check if the error is a WebSocket error
-			if _, ok := err.(*websocket.CloseError); ok {
-				// Reconnect the WebSocket
-				err = c.reconnect()
-				if err != nil {
-					log.Printf("failed to reconnect: %v", err)
-					return
-				}
-				continue
-			}
-			log.Printf("failed to read message: %v", err)
-			continue
+			c.logger.Error("failed to read message", "error", err)
+			return
 		}
 
-		switch messageType {
-		case websocket.TextMessage:
-			c.handleTextMessage(message)
-		case websocket.BinaryMessage:
-			fmt.Printf("Received binary message from server: %v\n", message)
-		default:
-			log.Printf("unexpected message type: %d", messageType)
-		}
+		_, _, isPrivate := c.session()
+		c.logger.Info("received message", "private", isPrivate, "method", msg.Method, "params", string(msg.Params))
 	}
 }
 
@@ -428,8 +946,11 @@ func (c *DeribitClient) Run() {
 
 type WebSocketResponse struct {
 	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id,omitempty"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
 }
 
 type ChannelInfo struct {
@@ -437,33 +958,32 @@ type ChannelInfo struct {
 	Data    json.RawMessage `json:"data"`
 }
 
+// ## Receive returns the next subscription notification or unmatched response,
+// it keeps waiting while the supervisor reconnects and fails once the client is closed
 func (c *DeribitClient) Receive() (*WebSocketResponse, error) {
-	// Read a message from the WebSocket connection
-	_, message, err := c.conn.ReadMessage()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read message: %w", err)
-	}
+	c.pendingMu.Lock()
+	done := c.done
+	c.pendingMu.Unlock()
 
-	// Parse the base WebSocket response
-	var wsResponse WebSocketResponse
-	if err := json.Unmarshal(message, &wsResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal WebSocket response: %w", err)
+	if done == nil {
+		return nil, errors.New("websocket is not connected")
 	}
 
-	// If the response is not a subscription, check is heartbeat, if not return as is
-	if wsResponse.Method != "subscription" {
-		if wsResponse.Method == "heartbeat" {
-			c.handleHeartbeat()
-		} else {
-			return &wsResponse, nil
-		}
+	// ## Drain what is already buffered before reporting a closed client
+	select {
+	case msg := <-c.notifications:
+		return msg, nil
+	default:
 	}
 
-	// Extract channel information
-	var channelInfo ChannelInfo
-	if err := json.Unmarshal(wsResponse.Params, &channelInfo); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal channel info: %w", err)
-	}
+	select {
+	case msg := <-c.notifications:
+		return msg, nil
+	case <-c.closed:
+		c.pendingMu.Lock()
+		err := c.closeErr
+		c.pendingMu.Unlock()
 
-	return &wsResponse, nil
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
 }
@@ -0,0 +1,41 @@
+package ws
+
+import "bitbucket.org/ohm89/go-deribit/deribit"
+
+// Error is the JSON-RPC error object returned by Deribit, the same type as api.Error
+// so errors.Is and errors.As work the same for both transports.
+type Error = deribit.Error
+
+// Deprecated: ResponseError is Error
+type ResponseError = Error
+
+// ## Sentinels for errors.Is, shared with the api package
+var (
+	ErrOrderNotFound      = deribit.ErrOrderNotFound
+	ErrNotEnoughFunds     = deribit.ErrNotEnoughFunds
+	ErrTooManyRequests    = deribit.ErrTooManyRequests
+	ErrRetry              = deribit.ErrRetry
+	ErrPriceWrongTick     = deribit.ErrPriceWrongTick
+	ErrInvalidCredentials = deribit.ErrInvalidCredentials
+	ErrUnauthorized       = deribit.ErrUnauthorized
+)
+
+func ErrorCode(err error) int {
+	return deribit.ErrorCode(err)
+}
+
+func IsRateLimited(err error) bool {
+	return deribit.IsRateLimited(err)
+}
+
+func IsInsufficientFunds(err error) bool {
+	return deribit.IsInsufficientFunds(err)
+}
+
+func IsOrderNotFound(err error) bool {
+	return deribit.IsOrderNotFound(err)
+}
+
+func IsUnauthorized(err error) bool {
+	return deribit.IsUnauthorized(err)
+}
@@ -0,0 +1,56 @@
+package ws
+
+import (
+	"log/slog"
+	"time"
+
+	"bitbucket.org/ohm89/go-deribit/deribit/ratelimit"
+)
+
+// Option configures a DeribitClient in NewDeribitClient
+type Option func(c *DeribitClient)
+
+// WithLogger sends the client logs to logger, the default is slog.Default().
+// Tokens and client secrets are never logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *DeribitClient) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithCallTimeout is the same as SetCallTimeout
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(c *DeribitClient) {
+		c.SetCallTimeout(timeout)
+	}
+}
+
+// WithReconnectPolicy is the same as SetReconnectPolicy
+func WithReconnectPolicy(policy ReconnectPolicy) Option {
+	return func(c *DeribitClient) {
+		c.SetReconnectPolicy(policy)
+	}
+}
+
+// WithSignatureAuth authenticates with grant_type=client_signature, the client secret is never sent
+func WithSignatureAuth() Option {
+	return func(c *DeribitClient) {
+		c.signatureAuth = true
+	}
+}
+
+// WithClockOffset is the offset added to the local time of signatures, SyncClock measures it
+func WithClockOffset(offset time.Duration) Option {
+	return func(c *DeribitClient) {
+		c.clock.SetOffset(offset)
+	}
+}
+
+// WithRateLimiter makes every Call wait for credits of limiter, share it with the api client of the same account
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(c *DeribitClient) {
+		c.limiter = limiter
+	}
+}
@@ -0,0 +1,419 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bitbucket.org/ohm89/go-deribit/deribit"
+)
+
+var (
+	// ErrBookGap is returned when prev_change_id does not follow the last applied change_id
+	ErrBookGap = errors.New("order book change_id gap")
+	// ErrBookNotSynced is returned for changes received before the first snapshot, they are kept for it
+	ErrBookNotSynced = errors.New("order book is waiting for snapshot")
+)
+
+// ## Changes kept while waiting for a snapshot, beyond that the oldest are dropped and the snapshot must be newer
+const maxPendingChanges = 10000
+
+// ## Depth of the MarketSnapshot order book, every level like the book channel
+const snapshotDepth = 10000
+
+type PriceLevel struct {
+	Price  float64
+	Amount float64
+}
+
+// SnapshotFunc loads a full book with its change_id, it is used to resync after a gap instead of resubscribing
+type SnapshotFunc func(ctx context.Context, instrumentName string) (*BookNotification, error)
+
+// OrderBook is the local book of one instrument built from book.{instrument}.{raw/100ms} notifications,
+// it is safe to read from any goroutine while the ws client applies updates.
+type OrderBook struct {
+	instrumentName string
+
+	mu        sync.RWMutex
+	bids      []PriceLevel // ## best (highest) first
+	asks      []PriceLevel // ## best (lowest) first
+	changeID  int64
+	timestamp int64
+	synced    bool
+
+	// ## changes received while not synced, replayed after the next snapshot
+	pending []*BookNotification
+}
+
+func NewOrderBook(instrumentName string) *OrderBook {
+	return &OrderBook{
+		instrumentName: instrumentName,
+	}
+}
+
+// ## Apply one snapshot or change notification. Changes received while not synced are kept and
+// the ones newer than the next snapshot are replayed on it, a REST snapshot is usually behind the stream.
+func (b *OrderBook) Apply(n *BookNotification) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n.Type == "snapshot" {
+		b.bids = b.bids[:0]
+		b.asks = b.asks[:0]
+		b.applyLevels(n)
+		b.synced = true
+		return b.replay()
+	}
+
+	if !b.synced {
+		b.keep(n)
+		return ErrBookNotSynced
+	}
+
+	return b.applyChange(n)
+}
+
+// ## applyChange applies a change that follows the last change_id, a gap unsyncs the book and keeps the change
+func (b *OrderBook) applyChange(n *BookNotification) error {
+	// ## Already included in a newer snapshot
+	if n.ChangeID <= b.changeID {
+		return nil
+	}
+
+	if n.PrevChangeID != b.changeID {
+		b.synced = false
+		b.keep(n)
+		return fmt.Errorf("%w: %s expected %d, got %d", ErrBookGap, b.instrumentName, b.changeID, n.PrevChangeID)
+	}
+
+	b.applyLevels(n)
+	return nil
+}
+
+// ## replay applies the kept changes after a snapshot, the ones it already includes are skipped
+func (b *OrderBook) replay() error {
+	pending := b.pending
+	b.pending = nil
+
+	for i, n := range pending {
+		if err := b.applyChange(n); err != nil {
+			// ## applyChange kept n, the changes after it wait for the next snapshot too
+			b.pending = append(b.pending, pending[i+1:]...)
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *OrderBook) keep(n *BookNotification) {
+	if len(b.pending) >= maxPendingChanges {
+		b.pending = append(b.pending[:0], b.pending[1:]...)
+	}
+	b.pending = append(b.pending, n)
+}
+
+func (b *OrderBook) applyLevels(n *BookNotification) {
+	for _, level := range n.Bids {
+		b.bids = updateLevel(b.bids, level, true)
+	}
+	for _, level := range n.Asks {
+		b.asks = updateLevel(b.asks, level, false)
+	}
+
+	b.changeID = n.ChangeID
+	b.timestamp = n.Timestamp
+}
+
+// ## Insert, replace or delete one price in a sorted side
+func updateLevel(levels []PriceLevel, level BookLevel, descending bool) []PriceLevel {
+	i := sort.Search(len(levels), func(i int) bool {
+		if descending {
+			return levels[i].Price <= level.Price
+		}
+		return levels[i].Price >= level.Price
+	})
+	found := i < len(levels) && levels[i].Price == level.Price
+
+	if level.Action == "delete" || level.Amount == 0 {
+		if found {
+			levels = append(levels[:i], levels[i+1:]...)
+		}
+		return levels
+	}
+
+	if found {
+		levels[i].Amount = level.Amount
+		return levels
+	}
+
+	levels = append(levels, PriceLevel{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = PriceLevel{Price: level.Price, Amount: level.Amount}
+	return levels
+}
+
+func (b *OrderBook) InstrumentName() string {
+	return b.instrumentName
+}
+
+// ## Synced is false until the first snapshot and after a gap until resync
+func (b *OrderBook) Synced() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.synced
+}
+
+func (b *OrderBook) ChangeID() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.changeID
+}
+
+func (b *OrderBook) Timestamp() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.timestamp
+}
+
+func (b *OrderBook) BestBid() (PriceLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.bids) == 0 {
+		return PriceLevel{}, false
+	}
+	return b.bids[0], true
+}
+
+func (b *OrderBook) BestAsk() (PriceLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.asks) == 0 {
+		return PriceLevel{}, false
+	}
+	return b.asks[0], true
+}
+
+// ## Depth returns a copy of the best n levels of each side
+func (b *OrderBook) Depth(n int) (bids []PriceLevel, asks []PriceLevel) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return copyLevels(b.bids, n), copyLevels(b.asks, n)
+}
+
+func copyLevels(levels []PriceLevel, n int) []PriceLevel {
+	if n <= 0 || n > len(levels) {
+		n = len(levels)
+	}
+	out := make([]PriceLevel, n)
+	copy(out, levels[:n])
+	return out
+}
+
+// ## CumulativeBidSize is the bid amount from the best bid down to price (what a sell can fill)
+func (b *OrderBook) CumulativeBidSize(price float64) float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var size float64
+	for _, level := range b.bids {
+		if level.Price < price {
+			break
+		}
+		size += level.Amount
+	}
+	return size
+}
+
+// ## CumulativeAskSize is the ask amount from the best ask up to price (what a buy can fill)
+func (b *OrderBook) CumulativeAskSize(price float64) float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var size float64
+	for _, level := range b.asks {
+		if level.Price > price {
+			break
+		}
+		size += level.Amount
+	}
+	return size
+}
+
+func (b *OrderBook) Mid() (float64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.bids) == 0 || len(b.asks) == 0 {
+		return 0, false
+	}
+	return (b.bids[0].Price + b.asks[0].Price) / 2, true
+}
+
+// ## Microprice is the mid weighted by the opposite side size of the top level
+func (b *OrderBook) Microprice() (float64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.bids) == 0 || len(b.asks) == 0 {
+		return 0, false
+	}
+
+	bid, ask := b.bids[0], b.asks[0]
+	if bid.Amount+ask.Amount == 0 {
+		return (bid.Price + ask.Price) / 2, true
+	}
+	return (bid.Price*ask.Amount + ask.Price*bid.Amount) / (bid.Amount + ask.Amount), true
+}
+
+// ## ----------------- Maintain book from subscription --------------
+
+// SubscribeOrderBook subscribes book.{instrument}.{interval} and keeps the returned book in sync.
+// On a change_id gap the book resyncs from snapshot when given, the changes received meanwhile are replayed on it.
+// Without snapshot, or when the kept changes do not reach back to it, the channel is resubscribed
+// and Deribit sends a fresh snapshot. A failed resync is retried with the reconnect backoff until the client closes.
+func (c *DeribitClient) SubscribeOrderBook(ctx context.Context, instrumentName, interval string, snapshot SnapshotFunc) (*OrderBook, error) {
+	book := NewOrderBook(instrumentName)
+	channel := BookChannel(instrumentName, interval)
+
+	var (
+		resyncing atomic.Bool
+		gaps      atomic.Int64
+	)
+	err := c.SubscribeBook(ctx, instrumentName, interval, func(n *BookNotification) {
+		err := book.Apply(n)
+		if !errors.Is(err, ErrBookGap) {
+			return
+		}
+
+		c.logger.Warn("order book gap, resync", "instrument_name", instrumentName, "error", err)
+		gaps.Add(1)
+
+		// ## Resync waits for Call responses, it cannot run on the read goroutine
+		if !resyncing.CompareAndSwap(false, true) {
+			return
+		}
+		go func() {
+			for {
+				seen := gaps.Load()
+				c.resyncOrderBookUntilDone(book, channel, snapshot)
+				resyncing.Store(false)
+
+				// ## A gap right after the resync found it still running, start the next one here
+				if gaps.Load() == seen || !resyncing.CompareAndSwap(false, true) {
+					return
+				}
+			}
+		}()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// ## resyncOrderBookUntilDone retries the resync with the reconnect backoff, a book left unsynced never
+// applies another change so it must not give up before the client closes
+func (c *DeribitClient) resyncOrderBookUntilDone(book *OrderBook, channel string, snapshot SnapshotFunc) {
+	c.mu.Lock()
+	policy := c.reconnectPolicy
+	c.mu.Unlock()
+
+	delay := policy.InitialDelay
+	for attempt := 1; ; attempt++ {
+		// ## The resync outlives the subscribe call, it is not bound to its ctx
+		err := c.resyncOrderBook(context.Background(), book, channel, snapshot)
+		if errors.Is(err, ErrBookGap) {
+			// ## The kept changes do not reach back to the snapshot, Deribit sends one in the stream instead
+			c.logger.Warn("order book snapshot behind the kept changes, resubscribe", "instrument_name", book.InstrumentName(), "error", err)
+			err = c.resyncOrderBook(context.Background(), book, channel, nil)
+		}
+		if err == nil {
+			return
+		}
+
+		c.logger.Error("failed to resync order book", "instrument_name", book.InstrumentName(), "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(jitter(delay)):
+		case <-c.closed:
+			return
+		}
+
+		delay *= 2
+		if delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+func (c *DeribitClient) resyncOrderBook(ctx context.Context, book *OrderBook, channel string, snapshot SnapshotFunc) error {
+	if snapshot != nil {
+		n, err := snapshot(ctx, book.InstrumentName())
+		if err == nil {
+			return book.Apply(n)
+		}
+		c.logger.Warn("failed to load order book snapshot, resubscribe instead", "instrument_name", book.InstrumentName(), "error", err)
+	}
+
+	ctx, cancel := c.callContext(ctx)
+	defer cancel()
+
+	// ## Keep the handler and channel set, only ask Deribit for a new snapshot
+	params := map[string]interface{}{
+		"channels": []string{channel},
+	}
+	if err := c.call(ctx, "public/unsubscribe", params, nil); err != nil {
+		return err
+	}
+	return c.call(ctx, "public/subscribe", params, nil)
+}
+
+// ## MarketSnapshot loads the resync snapshot with public/get_order_book over transport, e.g. an api.Client,
+// with every level so the book keeps the depth of the channel
+func MarketSnapshot(transport deribit.Transport) SnapshotFunc {
+	return func(ctx context.Context, instrumentName string) (*BookNotification, error) {
+		params := map[string]interface{}{
+			"instrument_name": instrumentName,
+			"depth":           snapshotDepth,
+		}
+
+		var result struct {
+			Timestamp      int64        `json:"timestamp"`
+			InstrumentName string       `json:"instrument_name"`
+			ChangeID       int64        `json:"change_id"`
+			Bids           [][2]float64 `json:"bids"`
+			Asks           [][2]float64 `json:"asks"`
+		}
+		if err := transport.Call(ctx, "public/get_order_book", params, &result); err != nil {
+			return nil, err
+		}
+
+		n := &BookNotification{
+			Type:           "snapshot",
+			Timestamp:      result.Timestamp,
+			InstrumentName: result.InstrumentName,
+			ChangeID:       result.ChangeID,
+			Bids:           make([]BookLevel, 0, len(result.Bids)),
+			Asks:           make([]BookLevel, 0, len(result.Asks)),
+		}
+		for _, bid := range result.Bids {
+			n.Bids = append(n.Bids, BookLevel{Action: "new", Price: bid[0], Amount: bid[1]})
+		}
+		for _, ask := range result.Asks {
+			n.Asks = append(n.Asks, BookLevel{Action: "new", Price: ask[0], Amount: ask[1]})
+		}
+
+		return n, nil
+	}
+}
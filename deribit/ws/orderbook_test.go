@@ -0,0 +1,190 @@
+package ws
+
+import (
+	"errors"
+	"testing"
+)
+
+func bookSnapshot(changeID int64, bids, asks []BookLevel) *BookNotification {
+	return &BookNotification{Type: "snapshot", InstrumentName: "BTC-PERPETUAL", ChangeID: changeID, Bids: bids, Asks: asks}
+}
+
+func bookChange(prevChangeID, changeID int64, bids, asks []BookLevel) *BookNotification {
+	return &BookNotification{Type: "change", InstrumentName: "BTC-PERPETUAL", PrevChangeID: prevChangeID, ChangeID: changeID, Bids: bids, Asks: asks}
+}
+
+func level(action string, price, amount float64) []BookLevel {
+	return []BookLevel{{Action: action, Price: price, Amount: amount}}
+}
+
+func assertBest(t *testing.T, book *OrderBook, bid, ask PriceLevel) {
+	t.Helper()
+
+	if got, _ := book.BestBid(); got != bid {
+		t.Errorf("best bid = %+v, want %+v", got, bid)
+	}
+	if got, _ := book.BestAsk(); got != ask {
+		t.Errorf("best ask = %+v, want %+v", got, ask)
+	}
+}
+
+func TestOrderBookApply(t *testing.T) {
+	book := NewOrderBook("BTC-PERPETUAL")
+
+	if err := book.Apply(bookSnapshot(10, level("new", 100, 1), level("new", 101, 2))); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Apply(bookChange(10, 11, level("new", 100.5, 3), nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Apply(bookChange(11, 12, nil, level("delete", 101, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Apply(bookChange(12, 13, nil, level("new", 102, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	if !book.Synced() || book.ChangeID() != 13 {
+		t.Fatalf("synced = %v, change_id = %d, want synced at 13", book.Synced(), book.ChangeID())
+	}
+	assertBest(t, book, PriceLevel{100.5, 3}, PriceLevel{102, 4})
+
+	bids, _ := book.Depth(0)
+	if len(bids) != 2 || bids[1] != (PriceLevel{100, 1}) {
+		t.Errorf("bids = %+v, want 100.5 then 100", bids)
+	}
+}
+
+func TestOrderBookGap(t *testing.T) {
+	book := NewOrderBook("BTC-PERPETUAL")
+
+	if err := book.Apply(bookSnapshot(10, level("new", 100, 1), level("new", 101, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	// ## change 11 is lost
+	err := book.Apply(bookChange(11, 12, level("new", 100, 5), nil))
+	if !errors.Is(err, ErrBookGap) {
+		t.Fatalf("Apply after a gap = %v, want ErrBookGap", err)
+	}
+	if book.Synced() {
+		t.Fatal("book is still synced after a gap")
+	}
+
+	err = book.Apply(bookChange(12, 13, level("new", 100, 6), nil))
+	if !errors.Is(err, ErrBookNotSynced) {
+		t.Fatalf("Apply while unsynced = %v, want ErrBookNotSynced", err)
+	}
+
+	// ## The levels stay at the last applied change until the resync
+	assertBest(t, book, PriceLevel{100, 1}, PriceLevel{101, 1})
+	if book.ChangeID() != 10 {
+		t.Errorf("change_id = %d, want 10", book.ChangeID())
+	}
+	if len(book.pending) != 2 {
+		t.Errorf("kept %d changes, want 2", len(book.pending))
+	}
+}
+
+func TestOrderBookSnapshotSkipsOlderChanges(t *testing.T) {
+	book := NewOrderBook("BTC-PERPETUAL")
+
+	if err := book.Apply(bookSnapshot(10, level("new", 100, 1), level("new", 101, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Apply(bookChange(11, 12, level("new", 100, 2), nil)); !errors.Is(err, ErrBookGap) {
+		t.Fatalf("Apply = %v, want ErrBookGap", err)
+	}
+	if err := book.Apply(bookChange(12, 13, level("new", 100, 3), nil)); !errors.Is(err, ErrBookNotSynced) {
+		t.Fatalf("Apply = %v, want ErrBookNotSynced", err)
+	}
+	if err := book.Apply(bookChange(13, 14, level("new", 100, 4), nil)); !errors.Is(err, ErrBookNotSynced) {
+		t.Fatalf("Apply = %v, want ErrBookNotSynced", err)
+	}
+
+	// ## The REST snapshot includes 11 to 13, only 14 is replayed on it
+	if err := book.Apply(bookSnapshot(13, level("new", 100, 30), level("new", 101, 1))); err != nil {
+		t.Fatalf("Apply snapshot = %v", err)
+	}
+
+	if !book.Synced() || book.ChangeID() != 14 {
+		t.Fatalf("synced = %v, change_id = %d, want synced at 14", book.Synced(), book.ChangeID())
+	}
+	assertBest(t, book, PriceLevel{100, 4}, PriceLevel{101, 1})
+	if len(book.pending) != 0 {
+		t.Errorf("kept %d changes after replay, want 0", len(book.pending))
+	}
+
+	if err := book.Apply(bookChange(14, 15, nil, level("change", 101, 2))); err != nil {
+		t.Fatal(err)
+	}
+	assertBest(t, book, PriceLevel{100, 4}, PriceLevel{101, 2})
+}
+
+func TestOrderBookSnapshotOlderThanKeptChanges(t *testing.T) {
+	book := NewOrderBook("BTC-PERPETUAL")
+
+	if err := book.Apply(bookSnapshot(10, level("new", 100, 1), level("new", 101, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Apply(bookChange(14, 15, level("new", 100, 2), nil)); !errors.Is(err, ErrBookGap) {
+		t.Fatalf("Apply = %v, want ErrBookGap", err)
+	}
+	if err := book.Apply(bookChange(15, 16, level("new", 100, 3), nil)); !errors.Is(err, ErrBookNotSynced) {
+		t.Fatalf("Apply = %v, want ErrBookNotSynced", err)
+	}
+
+	// ## The snapshot stops at 12, changes 13 and 14 are in neither
+	err := book.Apply(bookSnapshot(12, level("new", 100, 9), level("new", 101, 9)))
+	if !errors.Is(err, ErrBookGap) {
+		t.Fatalf("Apply of an old snapshot = %v, want ErrBookGap", err)
+	}
+	if book.Synced() {
+		t.Fatal("book is synced on a snapshot behind the kept changes")
+	}
+	if len(book.pending) != 2 {
+		t.Fatalf("kept %d changes, want 2 for the next snapshot", len(book.pending))
+	}
+
+	// ## A fresh snapshot from the stream syncs it again
+	if err := book.Apply(bookSnapshot(16, level("new", 100, 3), level("new", 101, 1))); err != nil {
+		t.Fatalf("Apply snapshot = %v", err)
+	}
+	if !book.Synced() || book.ChangeID() != 16 {
+		t.Fatalf("synced = %v, change_id = %d, want synced at 16", book.Synced(), book.ChangeID())
+	}
+}
+
+func TestOrderBookChangesBeforeFirstSnapshot(t *testing.T) {
+	book := NewOrderBook("BTC-PERPETUAL")
+
+	if err := book.Apply(bookChange(4, 5, level("new", 99, 1), nil)); !errors.Is(err, ErrBookNotSynced) {
+		t.Fatalf("Apply before snapshot = %v, want ErrBookNotSynced", err)
+	}
+	if err := book.Apply(bookChange(5, 6, level("new", 99.5, 1), nil)); !errors.Is(err, ErrBookNotSynced) {
+		t.Fatalf("Apply before snapshot = %v, want ErrBookNotSynced", err)
+	}
+
+	if err := book.Apply(bookSnapshot(5, level("new", 99, 1), level("new", 101, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if book.ChangeID() != 6 {
+		t.Errorf("change_id = %d, want 6", book.ChangeID())
+	}
+	assertBest(t, book, PriceLevel{99.5, 1}, PriceLevel{101, 1})
+}
+
+func TestOrderBookKeepDropsOldest(t *testing.T) {
+	book := NewOrderBook("BTC-PERPETUAL")
+
+	for i := int64(1); i <= maxPendingChanges+5; i++ {
+		book.Apply(bookChange(i-1, i, level("new", 100, float64(i)), nil))
+	}
+
+	if len(book.pending) != maxPendingChanges {
+		t.Fatalf("kept %d changes, want %d", len(book.pending), maxPendingChanges)
+	}
+	if first := book.pending[0].ChangeID; first != 6 {
+		t.Errorf("oldest kept change_id = %d, want 6", first)
+	}
+}
@@ -1,10 +1,10 @@
 package ws
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 
-	"github.com/gorilla/websocket"
+	"bitbucket.org/ohm89/go-deribit/deribit"
 )
 
 type OpenOrder struct {
@@ -61,33 +61,8 @@ type OpenOrder struct {
 	PrimaryOrderID        string      `json:"primary_order_id"`
 }
 
-type Position struct {
-	AveragePrice              float64 `json:"average_price"`
-	AveragePriceUSD           float64 `json:"average_price_usd"`
-	Delta                     float64 `json:"delta"`
-	Direction                 string  `json:"direction"`
-	EstimatedLiquidationPrice float64 `json:"estimated_liquidation_price"`
-	FloatingProfitLoss        float64 `json:"floating_profit_loss"`
-	FloatingProfitLossUSD     float64 `json:"floating_profit_loss_usd"`
-	Gamma                     float64 `json:"gamma"`
-	IndexPrice                float64 `json:"index_price"`
-	InitialMargin             float64 `json:"initial_margin"`
-	InstrumentName            string  `json:"instrument_name"`
-	InterestValue             float64 `json:"interest_value"`
-	Kind                      string  `json:"kind"`
-	Leverage                  int     `json:"leverage"`
-	MaintenanceMargin         float64 `json:"maintenance_margin"`
-	MarkPrice                 float64 `json:"mark_price"`
-	OpenOrdersMargin          float64 `json:"open_orders_margin"`
-	RealizedFunding           float64 `json:"realized_funding"`
-	RealizedProfitLoss        float64 `json:"realized_profit_loss"`
-	SettlementPrice           float64 `json:"settlement_price"`
-	Size                      float64 `json:"size"`
-	SizeCurrency              float64 `json:"size_currency"`
-	Theta                     float64 `json:"theta"`
-	TotalProfitLoss           float64 `json:"total_profit_loss"`
-	Vega                      float64 `json:"vega"`
-}
+// Position is deribit.Position, shared with the api client
+type Position = deribit.Position
 
 type PositionsResponse struct {
 	ID      int        `json:"id"`
@@ -107,57 +82,43 @@ type PositionResponse struct {
 * kind - future/option/spot/future_combo/option_combo
 * subaccountId - account id in integer
  */
-func GetPositions(client *DeribitClient, currency string, kind string) error {
-	// Create the request message
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "private/get_positions",
-		"params": map[string]interface{}{
-			"currency": currency,
-			"kind":     kind,
-		},
-	}
+func GetPositions(ctx context.Context, client *DeribitClient, currency string, kind string) (*PositionsResponse, error) {
+	ctx, cancel := client.callContext(ctx)
+	defer cancel()
 
-	// Marshal the request message to JSON
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request GetPositions message: %w", err)
+	// Create the request params
+	params := map[string]interface{}{
+		"currency": currency,
+		"kind":     kind,
 	}
 
-	// Send the request over the WebSocket
-	err = client.conn.WriteMessage(websocket.TextMessage, jsonMsg)
+	var resp PositionsResponse
+	err := client.call(ctx, "private/get_positions", params, &resp)
 	if err != nil {
-		return fmt.Errorf("failed to send GetPositions request: %w", err)
+		return nil, fmt.Errorf("failed to send GetPositions request: %w", err)
 	}
 
-	return nil
+	return &resp, nil
 }
 
 // ## Get One position list in account
 /**
 * instrument_name - BTC_USD/BTC-PERPEPTUAL
  */
-func GetPosition(client *DeribitClient, instrument_name string) error {
-	// Create the request message
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "private/get_position",
-		"params": map[string]interface{}{
-			"instrument_name": instrument_name,
-		},
-	}
+func GetPosition(ctx context.Context, client *DeribitClient, instrument_name string) (*PositionResponse, error) {
+	ctx, cancel := client.callContext(ctx)
+	defer cancel()
 
-	// Marshal the request message to JSON
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request GetPosition message: %w", err)
+	// Create the request params
+	params := map[string]interface{}{
+		"instrument_name": instrument_name,
 	}
 
-	// Send the request over the WebSocket
-	err = client.conn.WriteMessage(websocket.TextMessage, jsonMsg)
+	var resp PositionResponse
+	err := client.call(ctx, "private/get_position", params, &resp)
 	if err != nil {
-		return fmt.Errorf("failed to send GetPosition request: %w", err)
+		return nil, fmt.Errorf("failed to send GetPosition request: %w", err)
 	}
 
-	return nil
+	return &resp, nil
 }
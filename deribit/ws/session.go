@@ -0,0 +1,75 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ## Scoped sessions: exchange_token switches to a subaccount, fork_token opens a named session
+
+// ExchangeToken switches the connection to a session of subaccount subjectID. Reconnects restore it with
+// the new refresh token, when that refresh fails they authenticate with client credentials and exchange again.
+func ExchangeToken(ctx context.Context, c *DeribitClient, subjectID int64) (*AuthResponse, error) {
+	refreshToken, _, _ := c.session()
+	if refreshToken == "" {
+		return nil, errors.New("no refresh token, authenticate first")
+	}
+
+	params := map[string]interface{}{
+		"refresh_token": refreshToken,
+		"subject_id":    subjectID,
+	}
+
+	return sendToken(ctx, c, "public/exchange_token", params, "exchange_token", func(s *sessionScope) {
+		s.subjectID = subjectID
+	})
+}
+
+// ForkToken switches the connection to a new session named sessionName of the same account,
+// reconnects fork it again when they fall back to client credentials
+func ForkToken(ctx context.Context, c *DeribitClient, sessionName string) (*AuthResponse, error) {
+	refreshToken, _, _ := c.session()
+	if refreshToken == "" {
+		return nil, errors.New("no refresh token, authenticate first")
+	}
+
+	params := map[string]interface{}{
+		"refresh_token": refreshToken,
+		"session_name":  sessionName,
+	}
+
+	return sendToken(ctx, c, "public/fork_token", params, "fork_token", func(s *sessionScope) {
+		s.sessionName = sessionName
+	})
+}
+
+// ## sessionScope is what exchange_token and fork_token made of the session of the connection,
+// a reconnect that falls back to client credentials repeats them instead of trading on the main account
+type sessionScope struct {
+	subjectID   int64
+	sessionName string
+}
+
+// ## session returns the refresh token, scope and authentication state of the connection
+func (c *DeribitClient) session() (string, sessionScope, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.refreshToken, c.scope, c.isPrivate
+}
+
+// ## restoreScope repeats exchange_token and fork_token after an authentication with client credentials
+func (c *DeribitClient) restoreScope(ctx context.Context, scope sessionScope) error {
+	if scope.subjectID != 0 {
+		if _, err := ExchangeToken(ctx, c, scope.subjectID); err != nil {
+			return fmt.Errorf("failed to restore the session of subaccount %d: %w", scope.subjectID, err)
+		}
+	}
+	if scope.sessionName != "" {
+		if _, err := ForkToken(ctx, c, scope.sessionName); err != nil {
+			return fmt.Errorf("failed to restore session %s: %w", scope.sessionName, err)
+		}
+	}
+	return nil
+}
@@ -1,47 +1,18 @@
 package ws
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 
-	"github.com/gorilla/websocket"
+	"bitbucket.org/ohm89/go-deribit/deribit"
 )
 
-type PortfolioItem struct {
-	AdditionalReserve        float64 `json:"additional_reserve"`
-	AvailableFunds           float64 `json:"available_funds"`
-	AvailableWithdrawalFunds float64 `json:"available_withdrawal_funds"`
-	Balance                  float64 `json:"balance"`
-	Currency                 string  `json:"currency"`
-	Equity                   float64 `json:"equity"`
-	InitialMargin            float64 `json:"initial_margin"`
-	MaintenanceMargin        float64 `json:"maintenance_margin"`
-	MarginBalance            float64 `json:"margin_balance"`
-	SpotReserve              float64 `json:"spot_reserve"`
-}
-
-type Portfolio struct {
-	BTC PortfolioItem `json:"btc"`
-	ETH PortfolioItem `json:"eth"`
-}
-
-type SubAccount struct {
-	Email                   string    `json:"email"`
-	ID                      int       `json:"id"`
-	IsPassword              bool      `json:"is_password"`
-	LoginEnabled            bool      `json:"login_enabled"`
-	MarginModel             string    `json:"margin_model"`
-	NotConfirmedEmail       string    `json:"not_confirmed_email"`
-	Portfolio               Portfolio `json:"portfolio"`
-	ProofID                 string    `json:"proof_id"`
-	ProofIDSignature        string    `json:"proof_id_signature"`
-	ReceiveNotifications    bool      `json:"receive_notifications"`
-	SecurityKeysAssignments []string  `json:"security_keys_assignments"`
-	SecurityKeysEnabled     bool      `json:"security_keys_enabled"`
-	SystemName              string    `json:"system_name"`
-	Type                    string    `json:"type"`
-	Username                string    `json:"username"`
-}
+// ## Subaccount shapes are the deribit types shared with the api client
+type (
+	PortfolioItem = deribit.PortfolioItem
+	Portfolio     = deribit.Portfolio
+	SubAccount    = deribit.SubAccount
+)
 
 type SubAccountsResponse struct {
 	ID      int          `json:"id"`
@@ -56,62 +27,46 @@ type SubAccountsDetail struct {
 }
 
 type SubAccountsDetailsResponse struct {
-	ID      int               `json:"id"`
-	JSONRPC string            `json:"jsonrpc"`
-	Result  SubAccountsDetail `json:"result"`
+	ID      int                 `json:"id"`
+	JSONRPC string              `json:"jsonrpc"`
+	Result  []SubAccountsDetail `json:"result"`
 }
 
 // ## Get All Subaccounts Details
-func GetSubAccounts(client *DeribitClient, withPortfolio bool) error {
+func GetSubAccounts(ctx context.Context, client *DeribitClient, withPortfolio bool) (*SubAccountsResponse, error) {
+	ctx, cancel := client.callContext(ctx)
+	defer cancel()
 
-	// Create the request message
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "private/get_subaccounts",
-		"params": map[string]interface{}{
-			"with_portfolio": withPortfolio,
-		},
+	// Create the request params
+	params := map[string]interface{}{
+		"with_portfolio": withPortfolio,
 	}
 
-	// Marshal the request message to JSON
-	jsonMsg, err := json.Marshal(msg)
+	var resp SubAccountsResponse
+	err := client.call(ctx, "private/get_subaccounts", params, &resp)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request GetSubAccounts message: %w", err)
+		return nil, fmt.Errorf("failed to send GetSubAccounts request: %w", err)
 	}
 
-	// Send the request over the WebSocket
-	err = client.conn.WriteMessage(websocket.TextMessage, jsonMsg)
-	if err != nil {
-		return fmt.Errorf("failed to send GetSubAccounts request: %w", err)
-	}
-
-	return nil
+	return &resp, nil
 }
 
 // ## Get subaccounts positions
-func GetSubAccountsDetails(client *DeribitClient, currency string, withOpenOrders bool) error {
-
-	// Create the request message
-	msg := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  "private/get_subaccounts_details",
-		"params": map[string]interface{}{
-			"currency":         currency,
-			"with_open_orders": withOpenOrders,
-		},
-	}
-
-	// Marshal the request message to JSON
-	jsonMsg, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request GetSubAccountsDetails message: %w", err)
+func GetSubAccountsDetails(ctx context.Context, client *DeribitClient, currency string, withOpenOrders bool) (*SubAccountsDetailsResponse, error) {
+	ctx, cancel := client.callContext(ctx)
+	defer cancel()
+
+	// Create the request params
+	params := map[string]interface{}{
+		"currency":         currency,
+		"with_open_orders": withOpenOrders,
 	}
 
-	// Send the request over the WebSocket
-	err = client.conn.WriteMessage(websocket.TextMessage, jsonMsg)
+	var resp SubAccountsDetailsResponse
+	err := client.call(ctx, "private/get_subaccounts_details", params, &resp)
 	if err != nil {
-		return fmt.Errorf("failed to send GetSubAccountsDetails request: %w", err)
+		return nil, fmt.Errorf("failed to send GetSubAccountsDetails request: %w", err)
 	}
 
-	return nil
+	return &resp, nil
 }
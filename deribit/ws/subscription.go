@@ -0,0 +1,325 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ## Channel names, interval is raw/100ms/agg2 depending on the channel
+
+func TickerChannel(instrumentName, interval string) string {
+	return fmt.Sprintf("ticker.%s.%s", instrumentName, interval)
+}
+
+func BookChannel(instrumentName, interval string) string {
+	return fmt.Sprintf("book.%s.%s", instrumentName, interval)
+}
+
+func TradesChannel(instrumentName, interval string) string {
+	return fmt.Sprintf("trades.%s.%s", instrumentName, interval)
+}
+
+func TradesByKindChannel(kind, currency, interval string) string {
+	return fmt.Sprintf("trades.%s.%s.%s", kind, currency, interval)
+}
+
+func UserOrdersChannel(instrumentName, interval string) string {
+	return fmt.Sprintf("user.orders.%s.%s", instrumentName, interval)
+}
+
+func UserOrdersByKindChannel(kind, currency, interval string) string {
+	return fmt.Sprintf("user.orders.%s.%s.%s", kind, currency, interval)
+}
+
+func UserTradesChannel(instrumentName, interval string) string {
+	return fmt.Sprintf("user.trades.%s.%s", instrumentName, interval)
+}
+
+func UserTradesByKindChannel(kind, currency, interval string) string {
+	return fmt.Sprintf("user.trades.%s.%s.%s", kind, currency, interval)
+}
+
+func UserPortfolioChannel(currency string) string {
+	return fmt.Sprintf("user.portfolio.%s", currency)
+}
+
+func UserChangesChannel(instrumentName, interval string) string {
+	return fmt.Sprintf("user.changes.%s.%s", instrumentName, interval)
+}
+
+func UserChangesByKindChannel(kind, currency, interval string) string {
+	return fmt.Sprintf("user.changes.%s.%s.%s", kind, currency, interval)
+}
+
+func PriceIndexChannel(indexName string) string {
+	return fmt.Sprintf("deribit_price_index.%s", indexName)
+}
+
+func PerpetualChannel(instrumentName, interval string) string {
+	return fmt.Sprintf("perpetual.%s.%s", instrumentName, interval)
+}
+
+// ## ----------------- Notification data --------------
+
+type TickerStats struct {
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	PriceChange float64 `json:"price_change"`
+	Volume      float64 `json:"volume"`
+	VolumeUSD   float64 `json:"volume_usd"`
+}
+
+type TickerGreeks struct {
+	Delta float64 `json:"delta"`
+	Gamma float64 `json:"gamma"`
+	Rho   float64 `json:"rho"`
+	Theta float64 `json:"theta"`
+	Vega  float64 `json:"vega"`
+}
+
+type TickerNotification struct {
+	AskIV                  float64       `json:"ask_iv,omitempty"`
+	BestAskAmount          float64       `json:"best_ask_amount"`
+	BestAskPrice           float64       `json:"best_ask_price"`
+	BestBidAmount          float64       `json:"best_bid_amount"`
+	BestBidPrice           float64       `json:"best_bid_price"`
+	BidIV                  float64       `json:"bid_iv,omitempty"`
+	CurrentFunding         float64       `json:"current_funding,omitempty"`
+	EstimatedDeliveryPrice float64       `json:"estimated_delivery_price"`
+	Funding8h              float64       `json:"funding_8h,omitempty"`
+	Greeks                 *TickerGreeks `json:"greeks,omitempty"`
+	IndexPrice             float64       `json:"index_price"`
+	InstrumentName         string        `json:"instrument_name"`
+	InterestRate           float64       `json:"interest_rate,omitempty"`
+	InterestValue          float64       `json:"interest_value,omitempty"`
+	LastPrice              float64       `json:"last_price"`
+	MarkIV                 float64       `json:"mark_iv,omitempty"`
+	MarkPrice              float64       `json:"mark_price"`
+	MaxPrice               float64       `json:"max_price"`
+	MinPrice               float64       `json:"min_price"`
+	OpenInterest           float64       `json:"open_interest"`
+	SettlementPrice        float64       `json:"settlement_price,omitempty"`
+	State                  string        `json:"state"`
+	Stats                  TickerStats   `json:"stats"`
+	Timestamp              int64         `json:"timestamp"`
+	UnderlyingIndex        string        `json:"underlying_index,omitempty"`
+	UnderlyingPrice        float64       `json:"underlying_price,omitempty"`
+}
+
+// BookLevel is one [action, price, amount] entry of a book notification,
+// action is new/change/delete and amount is 0 for delete.
+type BookLevel struct {
+	Action string
+	Price  float64
+	Amount float64
+}
+
+func (l *BookLevel) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch len(raw) {
+	case 3:
+		if err := json.Unmarshal(raw[0], &l.Action); err != nil {
+			return err
+		}
+		raw = raw[1:]
+	case 2:
+		// ## Grouped book has no action
+		l.Action = ""
+	default:
+		return fmt.Errorf("unexpected book level length %d", len(raw))
+	}
+
+	if err := json.Unmarshal(raw[0], &l.Price); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[1], &l.Amount)
+}
+
+type BookNotification struct {
+	Type           string      `json:"type"`
+	Timestamp      int64       `json:"timestamp"`
+	InstrumentName string      `json:"instrument_name"`
+	ChangeID       int64       `json:"change_id"`
+	PrevChangeID   int64       `json:"prev_change_id,omitempty"`
+	Bids           []BookLevel `json:"bids"`
+	Asks           []BookLevel `json:"asks"`
+}
+
+type TradeNotification struct {
+	Amount         float64 `json:"amount"`
+	BlockTradeID   string  `json:"block_trade_id,omitempty"`
+	Contracts      float64 `json:"contracts,omitempty"`
+	Direction      string  `json:"direction"`
+	IndexPrice     float64 `json:"index_price"`
+	InstrumentName string  `json:"instrument_name"`
+	IV             float64 `json:"iv,omitempty"`
+	Liquidation    string  `json:"liquidation,omitempty"`
+	MarkPrice      float64 `json:"mark_price"`
+	Price          float64 `json:"price"`
+	TickDirection  int     `json:"tick_direction"`
+	Timestamp      int64   `json:"timestamp"`
+	TradeID        string  `json:"trade_id"`
+	TradeSeq       int     `json:"trade_seq"`
+}
+
+// UserOrdersNotification decodes both the single order of raw channels and the list of 100ms channels
+type UserOrdersNotification []OrderResultOrderResponse
+
+func (n *UserOrdersNotification) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var order OrderResultOrderResponse
+		if err := json.Unmarshal(data, &order); err != nil {
+			return err
+		}
+		*n = UserOrdersNotification{order}
+		return nil
+	}
+
+	var orders []OrderResultOrderResponse
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return err
+	}
+	*n = orders
+	return nil
+}
+
+type UserChangesNotification struct {
+	InstrumentName string                     `json:"instrument_name"`
+	Orders         []OrderResultOrderResponse `json:"orders"`
+	Positions      []Position                 `json:"positions"`
+	Trades         []OrderResultTradeResponse `json:"trades"`
+}
+
+type PriceIndexNotification struct {
+	IndexName string  `json:"index_name"`
+	Price     float64 `json:"price"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+type PerpetualNotification struct {
+	IndexPrice float64 `json:"index_price"`
+	Interest   float64 `json:"interest"`
+	Timestamp  int64   `json:"timestamp"`
+}
+
+// ## ----------------- Typed subscribe --------------
+// Handlers run on the read goroutine, they must return quickly and not wait for a Call response.
+
+func (c *DeribitClient) SubscribeTicker(ctx context.Context, instrumentName, interval string, handler func(*TickerNotification)) error {
+	return subscribeTyped(ctx, c, false, TickerChannel(instrumentName, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeBook(ctx context.Context, instrumentName, interval string, handler func(*BookNotification)) error {
+	return subscribeTyped(ctx, c, false, BookChannel(instrumentName, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeTrades(ctx context.Context, instrumentName, interval string, handler func([]TradeNotification)) error {
+	return subscribeTyped(ctx, c, false, TradesChannel(instrumentName, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeTradesByKind(ctx context.Context, kind, currency, interval string, handler func([]TradeNotification)) error {
+	return subscribeTyped(ctx, c, false, TradesByKindChannel(kind, currency, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeUserOrders(ctx context.Context, instrumentName, interval string, handler func(UserOrdersNotification)) error {
+	return subscribeTyped(ctx, c, true, UserOrdersChannel(instrumentName, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeUserOrdersByKind(ctx context.Context, kind, currency, interval string, handler func(UserOrdersNotification)) error {
+	return subscribeTyped(ctx, c, true, UserOrdersByKindChannel(kind, currency, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeUserTrades(ctx context.Context, instrumentName, interval string, handler func([]OrderResultTradeResponse)) error {
+	return subscribeTyped(ctx, c, true, UserTradesChannel(instrumentName, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeUserTradesByKind(ctx context.Context, kind, currency, interval string, handler func([]OrderResultTradeResponse)) error {
+	return subscribeTyped(ctx, c, true, UserTradesByKindChannel(kind, currency, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeUserPortfolio(ctx context.Context, currency string, handler func(*AccountSummary)) error {
+	return subscribeTyped(ctx, c, true, UserPortfolioChannel(currency), handler)
+}
+
+func (c *DeribitClient) SubscribeUserChanges(ctx context.Context, instrumentName, interval string, handler func(*UserChangesNotification)) error {
+	return subscribeTyped(ctx, c, true, UserChangesChannel(instrumentName, interval), handler)
+}
+
+func (c *DeribitClient) SubscribeUserChangesByKind(ctx context.Context, kind, currency, interval string, handler func(*UserChangesNotification)) error {
+	return subscribeTyped(ctx, c, true, UserChangesByKindChannel(kind, currency, interval), handler)
+}
+
+func (c *DeribitClient) SubscribePriceIndex(ctx context.Context, indexName string, handler func(*PriceIndexNotification)) error {
+	return subscribeTyped(ctx, c, false, PriceIndexChannel(indexName), handler)
+}
+
+func (c *DeribitClient) SubscribePerpetual(ctx context.Context, instrumentName, interval string, handler func(*PerpetualNotification)) error {
+	return subscribeTyped(ctx, c, false, PerpetualChannel(instrumentName, interval), handler)
+}
+
+// ## Register the decoder before subscribing so the first snapshot is not missed
+// T is a pointer or slice type, json allocates it on decode
+func subscribeTyped[T any](ctx context.Context, c *DeribitClient, private bool, channel string, handler func(T)) error {
+	c.setHandler(channel, func(data json.RawMessage) {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			c.logger.Error("failed to decode notification", "channel", channel, "error", err)
+			return
+		}
+		handler(v)
+	})
+
+	var err error
+	if private {
+		err = c.PrivateSubscribe(ctx, channel)
+	} else {
+		err = c.Subscribe(ctx, channel)
+	}
+	if err != nil {
+		c.removeHandlers(channel)
+		return err
+	}
+
+	return nil
+}
+
+func (c *DeribitClient) setHandler(channel string, handler func(data json.RawMessage)) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	c.handlers[channel] = handler
+}
+
+func (c *DeribitClient) removeHandlers(channels ...string) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	for _, ch := range channels {
+		delete(c.handlers, ch)
+	}
+}
+
+// ## Send subscription data to the typed handler, false when nobody registered the channel
+func (c *DeribitClient) dispatchSubscription(params json.RawMessage) bool {
+	var channelInfo ChannelInfo
+	if err := json.Unmarshal(params, &channelInfo); err != nil {
+		c.logger.Error("failed to unmarshal channel info", "error", err)
+		return false
+	}
+
+	c.handlersMu.RLock()
+	handler, ok := c.handlers[channelInfo.Channel]
+	c.handlersMu.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	handler(channelInfo.Data)
+	return true
+}
@@ -2,10 +2,13 @@ module bitbucket.org/ohm89/go-deribit
 
 go 1.24.0
 
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/valyala/fasthttp v1.59.0
+)
+
 require (
 	github.com/andybalholm/brotli v1.1.1 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/klauspost/compress v1.17.11 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
-	github.com/valyala/fasthttp v1.59.0 // indirect
 )
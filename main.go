@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -39,6 +40,8 @@ func main() {
 
 	fmt.Printf("\n\n********* Start Program %s *********** \n\n", config.NAME)
 
+	ctx := context.Background()
+
 	isUsePrivateWebSocket := false
 	isUseOrderAPI := true
 	isUseMarketAPI := false
@@ -56,28 +59,13 @@ func main() {
 		)
 
 		// ## ------- [Pre-Condition] ----
-		// orderBuyResponse, err := apiClient.Orders.Buy(
-		// 	"SOL_USDC-PERPETUAL",
-		// 	0.1,
-		// 	0,
-		// 	"market",
-		// 	"my-sol-usdc-future-perp-test",
-		// 	0,
-		// 	"good_til_cancelled",
-		// 	0,
-		// 	false,
-		// 	false,
-		// 	false,
-		// 	0,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	false,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	nil,
-		// )
+		// orderBuyResponse, err := apiClient.Orders.Buy(ctx, &api.OrderRequest{
+		// 	InstrumentName: "SOL_USDC-PERPETUAL",
+		// 	Amount:         0.1,
+		// 	Type:           "market",
+		// 	Label:          "my-sol-usdc-future-perp-test",
+		// 	TimeInForce:    "good_til_cancelled",
+		// })
 		// if err != nil {
 		// 	log.Fatalf("failed [Buy-Future] API: %+v", err)
 		// }
@@ -87,7 +75,7 @@ func main() {
 		// fmt.Printf("\n\n")
 
 		// ## ------- [GetPosition] ----
-		positionResponse, err := apiClient.Positions.GetPosition("SOL_USDC-PERPETUAL")
+		positionResponse, err := apiClient.Positions.GetPosition(ctx, "SOL_USDC-PERPETUAL")
 		if err != nil {
 			// Handle the error
 			log.Fatalf("failed [GetPosition] API: %+v", err)
@@ -101,7 +89,7 @@ func main() {
 		fmt.Println(" ")
 
 		// ## ------- [GetPositions] ----
-		positionsResponse, err := apiClient.Positions.GetPositions("USDC", "future", 0)
+		positionsResponse, err := apiClient.Positions.GetPositions(ctx, "USDC", "future", 0)
 		if err != nil {
 			// Handle the error
 			log.Fatalf("failed [GetPositions] API: %+v", err)
@@ -120,7 +108,7 @@ func main() {
 
 		// ## ------- [GetMargins] ----
 
-		marginsResponse, err := apiClient.Orders.GetMargins("SOL_USDC-PERPETUAL", 0.1, 139)
+		marginsResponse, err := apiClient.Orders.GetMargins(ctx, "SOL_USDC-PERPETUAL", 0.1, 139)
 		if err != nil {
 			// Handle the error
 			log.Fatalf("failed [GetMargins] API: %+v", err)
@@ -140,6 +128,7 @@ func main() {
 			"SOL_USDC-PERPETUAL": 100,
 		}
 		simulatePortfolioResponse, err := apiClient.Positions.GetSimulateMargins(
+			ctx,
 			"USDC",
 			true,
 			simulatedPositions,
@@ -159,6 +148,7 @@ func main() {
 
 		// ## ------- [GetSimulateMargins] ----
 		closePositionResponse, err := apiClient.Positions.ClosePosition(
+			ctx,
 			"SOL_USDC-PERPETUAL",
 			"market",
 			140,
@@ -185,20 +175,20 @@ func main() {
 		// // ## ------ Create Market Client connection --------------
 		// client := ws.NewDeribitClient(config.CLIENT_ID, config.CLIENT_SECRET)
 
-		// err := client.Connect(config.WS_URL)
+		// err := client.Connect(ctx, config.WS_URL)
 		// if err != nil {
 		// 	log.Fatalf("failed to connect: %v", err)
 		// }
 
 		// // ## Send Hello Software the WebSocket connection
-		// err = client.Hello(config.NAME, config.VERSION)
+		// err = client.Hello(ctx, config.NAME, config.VERSION)
 		// if err != nil {
 		// 	client.Close()
 		// 	log.Fatalf("failed to hello: %v", err)
 		// }
 
 		// // ## Set WebSocket HeartBeat Interval
-		// err = client.SetHeartBeat(60)
+		// err = client.SetHeartBeat(ctx, 60)
 		// if err != nil {
 		// 	client.Close()
 		// 	log.Fatalf("failed to set heart beat: %v", err)
@@ -206,6 +196,7 @@ func main() {
 
 		// // ## Subscribe to multiple channels
 		// err = client.Subscribe(
+		// 	ctx,
 		// 	"deribit_price_index.btc_usd",
 		// 	"deribit_price_index.btc_usdc",
 		// 	"deribit_price_index.btc_usdt",
@@ -219,27 +210,27 @@ func main() {
 
 		privateClient := ws.NewDeribitClient(config.CLIENT_ID, config.CLIENT_SECRET)
 
-		errPrivate := privateClient.Connect(config.WS_URL)
+		errPrivate := privateClient.Connect(ctx, config.WS_URL)
 		if errPrivate != nil {
 			log.Fatalf("failed to connect: %v", errPrivate)
 		}
 
 		// ## Send Hello Software the WebSocket connection
-		errPrivate = privateClient.Hello(config.NAME, config.VERSION)
+		errPrivate = privateClient.Hello(ctx, config.NAME, config.VERSION)
 		if errPrivate != nil {
 			privateClient.Close()
 			log.Fatalf("failed to hello: %v", errPrivate)
 		}
 
 		// ## Set WebSocket HeartBeat Interval
-		errPrivate = privateClient.SetHeartBeat(60)
+		errPrivate = privateClient.SetHeartBeat(ctx, 60)
 		if errPrivate != nil {
 			privateClient.Close()
 			log.Fatalf("failed to set heart beat: %v", errPrivate)
 		}
 
 		// ## Authenticate the WebSocket connection
-		_, err4 := ws.Authenticate(privateClient)
+		_, err4 := ws.Authenticate(ctx, privateClient)
 		if err4 != nil {
 			privateClient.Close()
 			log.Fatalf("failed to authenticate: %v", err4)
@@ -247,6 +238,7 @@ func main() {
 
 		// ## Subscribe to multiple channels
 		err := privateClient.PrivateSubscribe(
+			ctx,
 			// "deribit_price_index.sol_usdc", // public market index price data
 			// "trades.sol_usdc.raw",          // Trade Signal from our account
 			// "ticker.SOL_USDC.raw",          // ## Ticker of btc_usdc pairs [raw/100ms]
@@ -262,7 +254,7 @@ func main() {
 		}
 
 		// // ## UnSubscribe to all channels
-		// err = privateClient.PrivateUnsubscribeAll()
+		// err = privateClient.PrivateUnsubscribeAll(ctx)
 		// if err != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to PrivateUnsubscribeAll : %v", err)
@@ -279,7 +271,7 @@ func main() {
 		// 	Label:          "limit0000243",
 		// }
 
-		// errPrivate = ws.CreateBuyOrder(privateClient, orderRequest)
+		// _, errPrivate = ws.CreateBuyOrder(ctx, privateClient, orderRequest)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to create buy order: %v", errPrivate)
@@ -295,7 +287,7 @@ func main() {
 		// 	Label:          "limit0000243",
 		// }
 
-		// errPrivate = ws.CreateBuyOrder(privateClient, orderRequest)
+		// _, errPrivate = ws.CreateBuyOrder(ctx, privateClient, orderRequest)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to create buy order: %v", errPrivate)
@@ -309,7 +301,7 @@ func main() {
 		// 	Label:          "limit0000244",
 		// }
 
-		// errPrivate = ws.CreateBuyOrder(privateClient, orderRequest2)
+		// _, errPrivate = ws.CreateBuyOrder(ctx, privateClient, orderRequest2)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to create buy order: %v", errPrivate)
@@ -325,7 +317,7 @@ func main() {
 		// 	Label:          "limitSell0000243",
 		// }
 
-		// errPrivate = ws.CreateSellOrder(privateClient, orderRequest)
+		// _, errPrivate = ws.CreateSellOrder(ctx, privateClient, orderRequest)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to create buy order: %v", errPrivate)
@@ -339,7 +331,7 @@ func main() {
 		// 	Label:          "limitSell0000244",
 		// }
 
-		// errPrivate = ws.CreateSellOrder(privateClient, orderRequest2)
+		// _, errPrivate = ws.CreateSellOrder(ctx, privateClient, orderRequest2)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to create buy order: %v", errPrivate)
@@ -347,7 +339,7 @@ func main() {
 
 		// ## -------------- Test Cancel Order ---------------------
 
-		// errPrivate = ws.CancelOneOrder(privateClient, "BTC_USDC-2731791644")
+		// _, errPrivate = ws.CancelOneOrder(ctx, privateClient, "BTC_USDC-2731791644")
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to create buy order: %v", errPrivate)
@@ -355,7 +347,7 @@ func main() {
 
 		// ## -------------- Test Cancel All ---------------------
 
-		// errPrivate = ws.CancelAllOrders(privateClient)
+		// _, errPrivate = ws.CancelAllOrders(ctx, privateClient)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to create buy order: %v", errPrivate)
@@ -364,20 +356,20 @@ func main() {
 		// ## -------------- Test Get Account ---------------------
 
 		// // ## GEt All Currencies in account
-		// errPrivate = ws.GetAccountSummaries(privateClient, false)
+		// _, errPrivate = ws.GetAccountSummaries(ctx, privateClient, false)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to GetAccountSummaries: %v", errPrivate)
 		// }
 
 		// // ## Get One Currencies in account
-		// errPrivate = ws.GetAccountSummary(privateClient, "USDC", false)
+		// _, errPrivate = ws.GetAccountSummary(ctx, privateClient, "USDC", false)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to GetAccountSummary: %v", errPrivate)
 		// }
 
-		// errPrivate = ws.GetAccountSummary(privateClient, "BTC", false)
+		// _, errPrivate = ws.GetAccountSummary(ctx, privateClient, "BTC", false)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to GetAccountSummary: %v", errPrivate)
@@ -385,26 +377,26 @@ func main() {
 
 		// ## -------------- Test Get SubAccount ---------------------
 
-		// errPrivate = ws.GetSubAccounts(privateClient, false)
+		// _, errPrivate = ws.GetSubAccounts(ctx, privateClient, false)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to GetSubAccounts: %v", errPrivate)
 		// }
 
-		// errPrivate = ws.GetSubAccountsDetails(privateClient, "USDC", true)
+		// _, errPrivate = ws.GetSubAccountsDetails(ctx, privateClient, "USDC", true)
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to GetSubAccountsDetails: %v", errPrivate)
 		// }
 
 		// ## -------------- Test Get Position ---------------------
-		// errPrivate = ws.GetPositions(privateClient, "USDC", "future")
+		// _, errPrivate = ws.GetPositions(ctx, privateClient, "USDC", "future")
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to GetPositions: %v", errPrivate)
 		// }
 
-		// errPrivate = ws.GetPosition(privateClient, "BTC-PERPETUAL")
+		// _, errPrivate = ws.GetPosition(ctx, privateClient, "BTC-PERPETUAL")
 		// if errPrivate != nil {
 		// 	privateClient.Close()
 		// 	log.Fatalf("failed to GetPositions: %v", errPrivate)
@@ -438,28 +430,14 @@ func main() {
 			config.CLIENT_SECRET,
 		)
 
-		orderBuyResponse11, err := apiClient.Orders.PostBuy(
-			"BTC_USDC-PERPETUAL",
-			0.001,
-			0,
-			"limit",
-			"my-btc-usdc-perp-order",
-			18000.0,
-			"good_til_cancelled",
-			0,
-			false,
-			false,
-			false,
-			0,
-			0,
-			"",
-			"",
-			false,
-			0,
-			"",
-			"",
-			nil,
-		)
+		orderBuyResponse11, err := apiClient.Orders.PostBuy(ctx, &api.OrderRequest{
+			InstrumentName: "BTC_USDC-PERPETUAL",
+			Amount:         0.001,
+			Type:           "limit",
+			Label:          "my-btc-usdc-perp-order",
+			Price:          api.NewDecimal(18000.0),
+			TimeInForce:    "good_til_cancelled",
+		})
 		if err != nil {
 			log.Fatalf("failed [Buy] API: %+v", err)
 		}
@@ -468,28 +446,18 @@ func main() {
 		fmt.Printf("%#v", orderBuyResponse11)
 		fmt.Printf("\n\n")
 
-		orderResponse12, err := apiClient.Orders.PostSell(
-			"BTC_USDC-PERPETUAL",
-			0.001,
-			0,
-			"limit",
-			"my-btc-usdc-perp-sell-order",
-			180000.0,
-			"good_til_cancelled",
-			0,
-			false,
-			false,
-			false,
-			0,
-			0,
-			"",
-			"",
-			false,
-			0,
-			"",
-			"",
-			nil,
-		)
+		sellRequest, err := api.NewLimitOrder("BTC_USDC-PERPETUAL").
+			Amount(0.001).
+			Price(180000.0).
+			Label("my-btc-usdc-perp-sell-order").
+			TimeInForce(api.TimeInForceGoodTilCancelled).
+			PostOnly().
+			Build()
+		if err != nil {
+			log.Fatalf("failed [Sell] request: %+v", err)
+		}
+
+		orderResponse12, err := apiClient.Orders.PostSell(ctx, sellRequest)
 		if err != nil {
 			log.Fatalf("failed [Sell] API: %+v", err)
 		}
@@ -499,28 +467,14 @@ func main() {
 		fmt.Printf("\n\n")
 
 		// // ## ------- Test [Buy] Order ----------
-		// orderBuyResponse, err := apiClient.Orders.Buy(
-		// 	"BTC_USDC",
-		// 	0.0001,
-		// 	0,
-		// 	"limit",
-		// 	"my-btc-usdc-order",
-		// 	18000.0,
-		// 	"good_til_cancelled",
-		// 	0,
-		// 	false,
-		// 	false,
-		// 	false,
-		// 	0,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	false,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	nil,
-		// )
+		// orderBuyResponse, err := apiClient.Orders.Buy(ctx, &api.OrderRequest{
+		// 	InstrumentName: "BTC_USDC",
+		// 	Amount:         0.0001,
+		// 	Type:           "limit",
+		// 	Label:          "my-btc-usdc-order",
+		// 	Price:          18000.0,
+		// 	TimeInForce:    "good_til_cancelled",
+		// })
 		// if err != nil {
 		// 	log.Fatalf("failed [Buy] API: %+v", err)
 		// }
@@ -532,6 +486,7 @@ func main() {
 		// // ## ------- Test [CancelOneOrder] Order ----------
 		// if orderBuyResponse.Result.Order.OrderID != "" {
 		// 	orderCancelOneOrderResponse, err := apiClient.Orders.Cancel(
+		// 		ctx,
 		// 		orderBuyResponse.Result.Order.OrderID,
 		// 	)
 		// 	if err != nil {
@@ -544,28 +499,14 @@ func main() {
 		// }
 
 		// // ## ------- Test [Buy] Order 2 ----------
-		// orderBuyResponse2, err := apiClient.Orders.Buy(
-		// 	"BTC_USDC",
-		// 	0.0001,
-		// 	0,
-		// 	"limit",
-		// 	"my-btc-usdc-order",
-		// 	19000.0,
-		// 	"good_til_cancelled",
-		// 	0,
-		// 	false,
-		// 	false,
-		// 	false,
-		// 	0,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	false,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	nil,
-		// )
+		// orderBuyResponse2, err := apiClient.Orders.Buy(ctx, &api.OrderRequest{
+		// 	InstrumentName: "BTC_USDC",
+		// 	Amount:         0.0001,
+		// 	Type:           "limit",
+		// 	Label:          "my-btc-usdc-order",
+		// 	Price:          19000.0,
+		// 	TimeInForce:    "good_til_cancelled",
+		// })
 		// if err != nil {
 		// 	log.Fatalf("failed [Buy] API 2: %+v", err)
 		// }
@@ -576,7 +517,7 @@ func main() {
 
 		// // ## ------- Test [CancelAll] Order ----------
 
-		// orderCancelAllOrderResponse, err := apiClient.Orders.CancelAll()
+		// orderCancelAllOrderResponse, err := apiClient.Orders.CancelAll(ctx)
 		// if err != nil {
 		// 	log.Fatalf("failed [CancelAll] API: %+v", err)
 		// }
@@ -586,28 +527,14 @@ func main() {
 		// fmt.Printf("\n\n")
 
 		// // ## ------- Test [Buy] Order 3 ----------
-		// orderBuyResponse3, err := apiClient.Orders.Buy(
-		// 	"BTC_USDC",
-		// 	0.0001,
-		// 	0,
-		// 	"limit",
-		// 	"my-btc-usdc-order",
-		// 	20000.0,
-		// 	"good_til_cancelled",
-		// 	0,
-		// 	false,
-		// 	false,
-		// 	false,
-		// 	0,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	false,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	nil,
-		// )
+		// orderBuyResponse3, err := apiClient.Orders.Buy(ctx, &api.OrderRequest{
+		// 	InstrumentName: "BTC_USDC",
+		// 	Amount:         0.0001,
+		// 	Type:           "limit",
+		// 	Label:          "my-btc-usdc-order",
+		// 	Price:          20000.0,
+		// 	TimeInForce:    "good_til_cancelled",
+		// })
 		// if err != nil {
 		// 	log.Fatalf("failed [Buy] API 2: %+v", err)
 		// }
@@ -617,28 +544,14 @@ func main() {
 		// fmt.Printf("\n\n")
 
 		// // ## ------- Test [Buy] Order 4 ----------
-		// // orderBuyResponse4, err := apiClient.Orders.Buy(
-		// // 	"SOL_USDC",
-		// // 	1,
-		// // 	0,
-		// // 	"limit",
-		// // 	"my-btc-usdc-order",
-		// // 	10.0,
-		// // 	"good_til_cancelled",
-		// // 	0,
-		// // 	false,
-		// // 	false,
-		// // 	false,
-		// // 	0,
-		// // 	0,
-		// // 	"",
-		// // 	"",
-		// // 	false,
-		// // 	0,
-		// // 	"",
-		// // 	"",
-		// // 	nil,
-		// // )
+		// // orderBuyResponse4, err := apiClient.Orders.Buy(ctx, &api.OrderRequest{
+		// // 	InstrumentName: "SOL_USDC",
+		// // 	Amount:         1,
+		// // 	Type:           "limit",
+		// // 	Label:          "my-btc-usdc-order",
+		// // 	Price:          10.0,
+		// // 	TimeInForce:    "good_til_cancelled",
+		// // })
 		// // if err != nil {
 		// // 	log.Fatalf("failed [Buy] API 4: %+v", err)
 		// // }
@@ -650,6 +563,7 @@ func main() {
 		// // ## ------- Test [CancelByInstrument] Order ----------
 
 		// orderCancelByInstrumentResponse, err := apiClient.Orders.CancelAllByInstrument(
+		// 	ctx,
 		// 	"BTC_USDC",
 		// 	"all",
 		// 	false,
@@ -665,28 +579,14 @@ func main() {
 		// fmt.Printf("\n\n")
 
 		// // ## ------- Test [Buy] Order 6 ----------
-		// orderBuyResponse6, err := apiClient.Orders.Buy(
-		// 	"SOL_USDC",
-		// 	1,
-		// 	0,
-		// 	"limit",
-		// 	"my-sol-6-usdc-orde-001",
-		// 	10.0,
-		// 	"good_til_cancelled",
-		// 	0,
-		// 	false,
-		// 	false,
-		// 	false,
-		// 	0,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	false,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	nil,
-		// )
+		// orderBuyResponse6, err := apiClient.Orders.Buy(ctx, &api.OrderRequest{
+		// 	InstrumentName: "SOL_USDC",
+		// 	Amount:         1,
+		// 	Type:           "limit",
+		// 	Label:          "my-sol-6-usdc-orde-001",
+		// 	Price:          10.0,
+		// 	TimeInForce:    "good_til_cancelled",
+		// })
 		// if err != nil {
 		// 	log.Fatalf("failed [Buy] API 6: %+v", err)
 		// }
@@ -698,6 +598,7 @@ func main() {
 		// // ## ------- Test [GetOrderState] Order 6 ----------
 		// if orderBuyResponse6.Result.Order.OrderID != "" {
 		// 	orderState6, err := apiClient.Orders.GetOrderState(
+		// 		ctx,
 		// 		orderBuyResponse6.Result.Order.OrderID,
 		// 	)
 		// 	if err != nil {
@@ -712,6 +613,7 @@ func main() {
 		// // ## ------- Test [GetOrderStateByLabel] Order 6 ----------
 		// if orderBuyResponse6.Result.Order.OrderID != "" {
 		// 	orderState67, err := apiClient.Orders.GetOrderStateByLabel(
+		// 		ctx,
 		// 		"USDC",
 		// 		orderBuyResponse6.Result.Order.Label,
 		// 	)
@@ -727,6 +629,7 @@ func main() {
 		// // ## ------- Test [GetOpenOrders] Order 6 ----------
 
 		// openSpotOrderList, err := apiClient.Orders.GetOpenOrders(
+		// 	ctx,
 		// 	"spot",
 		// 	"all",
 		// )
@@ -741,6 +644,7 @@ func main() {
 		// // ## ------- Test [GetOpenOrdersByInstrument] Order 6 ----------
 
 		// openSpotOrderList2, err := apiClient.Orders.GetOpenOrdersByInstrument(
+		// 	ctx,
 		// 	"SOL_USDC",
 		// 	"all",
 		// )
@@ -755,6 +659,7 @@ func main() {
 		// // ## ------- Test [GetOrderHistoryByCurrency] Order 6 ----------
 
 		// orderHistoryList1, err := apiClient.Orders.GetOrderHistoryByCurrency(
+		// 	ctx,
 		// 	"USDC",
 		// 	"spot",
 		// 	20,
@@ -773,6 +678,7 @@ func main() {
 		// // ## ------- Test [GetOrderHistoryByInstrument] Order 6 ----------
 
 		// orderHistoryList2, err := apiClient.Orders.GetOrderHistoryByInstrument(
+		// 	ctx,
 		// 	"SOL_USDC",
 		// 	20,
 		// 	0,
@@ -790,6 +696,7 @@ func main() {
 		// // ## ------- Test [GetTriggerOrderHistory] Order 6 ----------
 
 		// triggerOrderHistoryList1, err := apiClient.Orders.GetTriggerOrderHistory(
+		// 	ctx,
 		// 	"USDC",
 		// 	"",
 		// 	20,
@@ -805,7 +712,7 @@ func main() {
 
 		// // ## ------- Test [CancelAll] Order ----------
 
-		// orderCancelAllLast, err := apiClient.Orders.CancelAll()
+		// orderCancelAllLast, err := apiClient.Orders.CancelAll(ctx)
 		// if err != nil {
 		// 	log.Fatalf("failed [CancelAll] API: %+v", err)
 		// }
@@ -815,28 +722,14 @@ func main() {
 		// fmt.Printf("\n\n")
 
 		// ## ------- Test [Sell] Order ----------
-		// orderResponse2, err := apiClient.Orders.Sell(
-		// 	"BTC_USDC",
-		// 	0.0001,
-		// 	0,
-		// 	"limit",
-		// 	"my-btc-usdc-order",
-		// 	180000.0,
-		// 	"good_til_cancelled",
-		// 	0,
-		// 	false,
-		// 	false,
-		// 	false,
-		// 	0,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	false,
-		// 	0,
-		// 	"",
-		// 	"",
-		// 	nil,
-		// )
+		// orderResponse2, err := apiClient.Orders.Sell(ctx, &api.OrderRequest{
+		// 	InstrumentName: "BTC_USDC",
+		// 	Amount:         0.0001,
+		// 	Type:           "limit",
+		// 	Label:          "my-btc-usdc-order",
+		// 	Price:          180000.0,
+		// 	TimeInForce:    "good_til_cancelled",
+		// })
 		// if err != nil {
 		// 	log.Fatalf("failed [Sell] API: %+v", err)
 		// }
@@ -846,7 +739,7 @@ func main() {
 		// fmt.Printf("\n\n")
 
 		// ## ------- Test [GetAccountSummaries] ----------
-		// dataResponse, err := apiClient.Accounts.GetAccountSummaries(true)
+		// dataResponse, err := apiClient.Accounts.GetAccountSummaries(ctx, true)
 		// if err != nil {
 		// 	log.Fatalf("failed [GetAccountSummaries] API: %v", err)
 		// }
@@ -856,7 +749,7 @@ func main() {
 		// fmt.Printf("\n\n")
 
 		// ## ------- Test [GetAccountSummary] ----------
-		// dataResponse2, err := apiClient.Accounts.GetAccountSummary("USDC", true)
+		// dataResponse2, err := apiClient.Accounts.GetAccountSummary(ctx, "USDC", true)
 		// if err != nil {
 		// 	log.Fatalf("failed [GetAccountSummary] API: %v", err)
 		// }
@@ -884,7 +777,7 @@ func main() {
 			Length:         "8h", // Can be "8h", "24h", or "1m"
 		}
 
-		fundingChartDataResponse, err := apiClient.Markets.GetFundingChartData(requestFundingChartData)
+		fundingChartDataResponse, err := apiClient.Markets.GetFundingChartData(ctx, requestFundingChartData)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetFundingChartData]: %+v", err)
@@ -907,6 +800,7 @@ func main() {
 		instrumentName := "BTC-PERPETUAL" // September 27, 2019 00:00:00 UTC
 
 		fundingRateHistoryResponse, err := apiClient.Markets.GetFundingRateHistory(
+			ctx,
 			instrumentName,
 			startTimestamp,
 			endTimestamp,
@@ -934,6 +828,7 @@ func main() {
 		instrumentName = "BTC-PERPETUAL" // September 27, 2019 00:00:00 UTC
 
 		fundingRateValueResponse, err := apiClient.Markets.GetFundingRateValue(
+			ctx,
 			instrumentName,
 			startTimestamp,
 			endTimestamp,
@@ -954,7 +849,7 @@ func main() {
 		// @@ ------------ [4] GetHistoricalVolatility --------
 		currency := "BTC"
 
-		historicalVolatilityResponse, err := apiClient.Markets.GetHistoricalVolatility(currency)
+		historicalVolatilityResponse, err := apiClient.Markets.GetHistoricalVolatility(ctx, currency)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetHistoricalVolatility]: %+v", err)
@@ -976,7 +871,7 @@ func main() {
 		// Example usage of GetIndexPrice
 		indexName := "btc_usd"
 
-		indexPriceResponse, err := apiClient.Markets.GetIndexPrice(indexName)
+		indexPriceResponse, err := apiClient.Markets.GetIndexPrice(ctx, indexName)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetIndexPrice]: %+v", err)
@@ -988,7 +883,7 @@ func main() {
 		fmt.Println("")
 
 		// @@ ------------ [6] GetIndexPriceNames --------
-		indexPriceNamesResponse, err := apiClient.Markets.GetIndexPriceNames()
+		indexPriceNamesResponse, err := apiClient.Markets.GetIndexPriceNames(ctx)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetIndexPriceNames]: %+v", err)
@@ -1005,7 +900,7 @@ func main() {
 		// @@ ------------ [7] GetInstrument --------
 		instrumentName = "BTC-PERPETUAL"
 
-		instrumentResponse, err := apiClient.Markets.GetInstrument(instrumentName)
+		instrumentResponse, err := apiClient.Markets.GetInstrument(ctx, instrumentName)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetInstrument]: %+v", err)
@@ -1023,7 +918,7 @@ func main() {
 		kind := "spot"
 		expired := false
 
-		instrumentsResponse, err := apiClient.Markets.GetInstruments(currency, kind, expired)
+		instrumentsResponse, err := apiClient.Markets.GetInstruments(ctx, currency, kind, expired)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetInstruments]: %+v", err)
@@ -1065,7 +960,7 @@ func main() {
 		continuation := ""
 		searchStartTimestamp := time.Now().Add(-7*24*time.Hour).Unix() * 1000 // 7 days ago
 
-		lastSettlementsResponse, err := apiClient.Markets.GetLastSettlementsByInstrument(instrumentName, settlementType, count, continuation, searchStartTimestamp)
+		lastSettlementsResponse, err := apiClient.Markets.GetLastSettlementsByInstrument(ctx, instrumentName, settlementType, count, continuation, searchStartTimestamp)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetLastSettlementsByInstrument]: %+v", err)
@@ -1090,7 +985,7 @@ func main() {
 		endTimestamp = time.Now().Unix() * 1000                     // Current timestamp
 		count = 5
 
-		lastTradesByCurrencyAndTimeResponse, err := apiClient.Markets.GetLastTradesByCurrencyAndTime(currency, startTimestamp, endTimestamp, count)
+		lastTradesByCurrencyAndTimeResponse, err := apiClient.Markets.GetLastTradesByCurrencyAndTime(ctx, currency, startTimestamp, endTimestamp, count)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetLastTradesByCurrencyAndTime]: %+v", err)
@@ -1122,7 +1017,7 @@ func main() {
 		count = 10
 		sorting := "default"
 
-		lastTradesByInstrumentResponse, err := apiClient.Markets.GetLastTradesByInstrument(instrumentName, startSeq, endSeq, startTimestamp, endTimestamp, count, sorting)
+		lastTradesByInstrumentResponse, err := apiClient.Markets.GetLastTradesByInstrument(ctx, instrumentName, startSeq, endSeq, startTimestamp, endTimestamp, count, sorting)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetLastTradesByInstrument]: %+v", err)
@@ -1155,7 +1050,7 @@ func main() {
 		count = 10
 		sorting = "default"
 
-		lastTradesByInstrumentAndTimeResponse, err := apiClient.Markets.GetLastTradesByInstrumentAndTime(instrumentName, startTimestamp, endTimestamp, count, sorting)
+		lastTradesByInstrumentAndTimeResponse, err := apiClient.Markets.GetLastTradesByInstrumentAndTime(ctx, instrumentName, startTimestamp, endTimestamp, count, sorting)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetLastTradesByInstrumentAndTime]: %+v", err)
@@ -1185,7 +1080,7 @@ func main() {
 		endTimestamp = time.Now().Unix() * 1000                     // Current timestamp
 		// resolution := "5min"
 
-		markPriceHistoryResponse, err := apiClient.Markets.GetMarkPriceHistory(instrumentName, startTimestamp, endTimestamp)
+		markPriceHistoryResponse, err := apiClient.Markets.GetMarkPriceHistory(ctx, instrumentName, startTimestamp, endTimestamp)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetMarkPriceHistory]: %+v", err)
@@ -1208,7 +1103,7 @@ func main() {
 		instrumentName = "BTC_USDC"
 		depth := 5
 
-		orderBookResponse, err := apiClient.Markets.GetOrderBook(instrumentName, depth)
+		orderBookResponse, err := apiClient.Markets.GetOrderBook(ctx, instrumentName, depth)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetOrderBook]: %+v", err)
@@ -1244,7 +1139,7 @@ func main() {
 		}
 		depth = 10
 
-		orderBookByInstrumentResponse, err := apiClient.Markets.GetOrderBookByInstrumentId(instrumentID, depth)
+		orderBookByInstrumentResponse, err := apiClient.Markets.GetOrderBookByInstrumentId(ctx, instrumentID, depth)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetOrderBookByInstrumentId]: %+v", err)
@@ -1276,7 +1171,7 @@ func main() {
 		// @@ ------------ [16] GetTradeVolumes --------
 		extended := true
 
-		tradeVolumesResponse, err := apiClient.Markets.GetTradeVolumes(extended)
+		tradeVolumesResponse, err := apiClient.Markets.GetTradeVolumes(ctx, extended)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetTradeVolumes]: %+v", err)
@@ -1311,7 +1206,7 @@ func main() {
 		endTimestamp = time.Now().Unix() * 1000                     // Current timestamp
 		resolution := "30"
 
-		tradingViewChartDataResponse, err := apiClient.Markets.GetTradingViewChartData(instrumentName, startTimestamp, endTimestamp, resolution)
+		tradingViewChartDataResponse, err := apiClient.Markets.GetTradingViewChartData(ctx, instrumentName, startTimestamp, endTimestamp, resolution)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetTradingViewChartData]: %+v", err)
@@ -1360,7 +1255,7 @@ func main() {
 		endTimestamp = time.Now().Unix() * 1000                      // Current timestamp
 		resolution = "1D"
 
-		volatilityIndexDataResponse, err := apiClient.Markets.GetVolatilityIndexData(currency, startTimestamp, endTimestamp, resolution)
+		volatilityIndexDataResponse, err := apiClient.Markets.GetVolatilityIndexData(ctx, currency, startTimestamp, endTimestamp, resolution)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetVolatilityIndexData]: %+v", err)
@@ -1386,7 +1281,7 @@ func main() {
 		// @@ ------------ [19] GetTicker --------
 		instrumentName = "BTC_USDC"
 
-		tickerResponse, err := apiClient.Markets.GetTicker(instrumentName)
+		tickerResponse, err := apiClient.Markets.GetTicker(ctx, instrumentName)
 		if err != nil {
 			// Handle error
 			log.Fatalf("failed [GetTicker]: %+v", err)
@@ -1428,20 +1323,20 @@ func main() {
 		// ## ------ Create Market Client connection --------------
 		client := ws.NewDeribitClient(config.CLIENT_ID, config.CLIENT_SECRET)
 
-		err := client.Connect(config.WS_URL)
+		err := client.Connect(ctx, config.WS_URL)
 		if err != nil {
 			log.Fatalf("failed to connect: %v", err)
 		}
 
 		// ## Send Hello Software the WebSocket connection
-		err = client.Hello(config.NAME, config.VERSION)
+		err = client.Hello(ctx, config.NAME, config.VERSION)
 		if err != nil {
 			client.Close()
 			log.Fatalf("failed to hello: %v", err)
 		}
 
 		// ## Set WebSocket HeartBeat Interval
-		err = client.SetHeartBeat(60)
+		err = client.SetHeartBeat(ctx, 60)
 		if err != nil {
 			client.Close()
 			log.Fatalf("failed to set heart beat: %v", err)
@@ -1449,11 +1344,13 @@ func main() {
 
 		// ## Subscribe to multiple channels
 		// err = client.Subscribe(
+		// 	ctx,
 		// 	"deribit_price_index.btc_usd",
 		// 	"deribit_price_index.btc_usdc",
 		// 	"deribit_price_index.btc_usdt",
 		// )
 		err = client.Subscribe(
+			ctx,
 			"deribit_price_index.btc_usdc",      // ## Index price
 			"deribit_volatility_index.btc_usdc", // ## Volatility Index
 			"deribit_price_statistics.btc_usdc", // ## Volatility Index
@@ -1470,6 +1367,7 @@ func main() {
 
 		// ## UnSubscribe to multiple channels
 		err = client.Unsubscribe(
+			ctx,
 			"deribit_price_index.btc_usdt",
 		)
 		if err != nil {
@@ -1477,6 +1375,15 @@ func main() {
 			log.Fatalf("failed to Unsubscribe : %v", err)
 		}
 
+		// ## Typed subscription, decoded ticker is sent to the handler instead of Receive
+		err = client.SubscribeTicker(ctx, "BTC_USDC", "100ms", func(ticker *ws.TickerNotification) {
+			fmt.Printf("Ticker %s bid: %f ask: %f \n", ticker.InstrumentName, ticker.BestBidPrice, ticker.BestAskPrice)
+		})
+		if err != nil {
+			client.Close()
+			log.Fatalf("failed to SubscribeTicker : %v", err)
+		}
+
 		// ## -------------- Main Loop (Concurrent GO) ---------------------
 		// Start concurrent tasks for HandleReadMessage and HandleHeartBeatMessage
 		// Start concurrent tasks for HandleReadMessage and HandleHeartBeatMessage